@@ -32,6 +32,7 @@ func main() {
 
 	// run server
 	go application.GRPCSrv.MustRun()
+	go application.HTTPSrv.MustRun()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	jwkshttp "github.com/rautaruukkipalich/go_auth_grpc/internal/http/jwks"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/secretbox"
+	keyssrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/keys"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage/sqlstorage"
+)
+
+const usage = `usage: authctl --config=path [--alg=EdDSA] <command>
+
+commands:
+  keys list     list signing keys
+  keys add      add pending key, it is published in JWKS but does not sign tokens
+  keys rotate   activate pending key published long enough, active key becomes retiring;
+                pending key is added if there is none, run rotate again after it is published
+  keys retire   retire keys which are retiring longer than token ttl
+`
+
+func main() {
+	alg := flag.String("alg", "", "algorithm of new keys: RS256, ES256 or EdDSA")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	cfg := config.MustLoadConfig()
+
+	args := flag.Args()
+	if len(args) != 2 || args[0] != "keys" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *alg == "" {
+		*alg = cfg.Signing.Algorithm
+	}
+
+	dbURI := cfg.Database.URI()
+	if dbURI == "" {
+		panic("invalid database driver")
+	}
+
+	storage, err := sqlstorage.New(dbURI)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	var secrets *secretbox.Box
+	if cfg.Signing.EncryptionKey != "" {
+		secrets, err = secretbox.New(cfg.Signing.EncryptionKey)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	keys := keyssrvcs.New(
+		log,
+		storage,
+		jwt.NewKeyring(nil),
+		nil,
+		secrets,
+		cfg.Token.TTL,
+		cfg.Signing.ActivationDelay(jwkshttp.MaxAge),
+	)
+
+	ctx := context.Background()
+
+	switch args[1] {
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			panic(err)
+		}
+		for _, key := range list {
+			printKey(key)
+		}
+	case "add":
+		key, err := keys.Add(ctx, *alg)
+		if err != nil {
+			panic(err)
+		}
+		printKey(key)
+	case "rotate":
+		key, err := keys.Rotate(ctx, *alg)
+		if errors.Is(err, keyssrvcs.ErrKeyNotReady) {
+			printKey(key)
+			fmt.Printf("key is pending, run rotate again after %s\n", keys.ActivatesAt(key).Local().Format(time.RFC3339))
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		printKey(key)
+		fmt.Printf("previous key is accepted for %s\n", cfg.Token.TTL)
+	case "retire":
+		count, err := keys.Retire(ctx)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d keys retired\n", count)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printKey(key models.SigningKey) {
+	created := key.CreatedAt.Format(time.RFC3339)
+	if key.CreatedAt.IsZero() {
+		created = "now"
+	}
+	fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, key.Algorithm, key.State, created)
+}
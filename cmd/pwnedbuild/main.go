@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pwned"
+)
+
+const usage = `usage: pwnedbuild --in=pwned-passwords.txt --out=pwned.bloom [--fp=0.001] [--min-count=1]
+
+builds bloom filter of SHA-1 hash list, one "HASH" or "HASH:COUNT" per line,
+e.g. Pwned Passwords ordered by hash. Set breached_passwords.path to output file.
+
+`
+
+func main() {
+	in := flag.String("in", "", "hash list")
+	out := flag.String("out", "", "bloom filter file")
+	fp := flag.Float64("fp", 0.001, "false positive rate")
+	minCount := flag.Int("min-count", 1, "skip hashes seen fewer times in breaches")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *in == "" || *out == "" || *fp <= 0 || *fp >= 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// first pass sizes filter, second one fills it
+	var n uint64
+	err := forEachHash(*in, *minCount, func(pwned.Hash) { n++ })
+	if err != nil {
+		panic(err)
+	}
+
+	bloom := pwned.NewBloom(n, *fp)
+	err = forEachHash(*in, *minCount, bloom.Add)
+	if err != nil {
+		panic(err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		panic(err)
+	}
+
+	w := bufio.NewWriter(f)
+	size, err := bloom.WriteTo(w)
+	if err != nil {
+		panic(err)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%d hashes, %d bytes\n", n, size)
+}
+
+// forEachHash calls fn with hashes seen at least minCount times.
+// Lines without count are always taken.
+func forEachHash(path string, minCount int, fn func(pwned.Hash)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if _, count, ok := strings.Cut(text, ":"); ok && minCount > 1 {
+			c, err := strconv.Atoi(count)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid count: %w", line, err)
+			}
+			if c < minCount {
+				continue
+			}
+		}
+
+		h, err := pwned.ParseHash(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		fn(h)
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,3 @@
+module github.com/rautaruukkipalich/go_auth_grpc_contract
+
+go 1.21.0
@@ -1,33 +1,267 @@
 package app
 
 import (
+	"context"
 	"log/slog"
+	"os"
 
 	grpcapp "github.com/rautaruukkipalich/go_auth_grpc/internal/app/grpc"
+	httpapp "github.com/rautaruukkipalich/go_auth_grpc/internal/app/http"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
+	jwkshttp "github.com/rautaruukkipalich/go_auth_grpc/internal/http/jwks"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passhash"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passkey"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passpolicy"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pow"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pwned"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/ratelimit"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/secretbox"
+	authsrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/auth"
+	keyssrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/keys"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage/sqlstorage"
 )
 
+type DBCloser interface {
+	Close()
+}
+
 type App struct {
-	GRPCSrv *grpcapp.App
+	GRPCSrv   *grpcapp.App
+	HTTPSrv   *httpapp.App
+	broker    kafka.Brokerer
+	db        DBCloser
+	stopWatch context.CancelFunc
 }
 
 func New(
 	log *slog.Logger,
 	cfg *config.Config,
 ) *App {
-	// init storage 
-	// init service auth
+	dbURI := cfg.Database.URI()
+	if dbURI == "" {
+		panic("invalid database driver")
+	}
+
+	// init storage
+	storage, err := sqlstorage.New(dbURI)
+	if err != nil {
+		panic(err)
+	}
+
+	// init signing keys
+	keyring := jwt.NewKeyring(nil)
+	keysService := keyssrvcs.New(
+		log,
+		storage,
+		keyring,
+		mustLoadKey(cfg.Signing),
+		mustLoadSecrets(cfg.Signing.EncryptionKey),
+		cfg.Token.TTL,
+		cfg.Signing.ActivationDelay(jwkshttp.MaxAge),
+	)
+	if err := keysService.Reload(context.Background()); err != nil {
+		panic(err)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go keysService.Watch(watchCtx, cfg.Signing.ReloadInterval)
 
 	broker := kafka.New(log)
-	grpcApp := grpcapp.New(log, cfg, broker)
-	
+
+	// init service auth
+	auth := authsrvcs.New(
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		keyring,
+		mustLoadSecrets(cfg.MFA.EncryptionKey),
+		mustLoadPasskeys(cfg.WebAuthn),
+		mustLoadPoW(cfg.PoW, storage),
+		mustLoadHasher(cfg.Password),
+		passwordPolicy(cfg.PasswordPolicy),
+		mustLoadBreached(cfg.Breached),
+		log,
+		cfg.Token,
+		cfg.MFA,
+		cfg.Lockout,
+		cfg.Password,
+		broker,
+	)
+
+	grpcApp := grpcapp.New(log, cfg, auth, rateLimiter(cfg.RateLimit, storage))
+	httpApp := httpapp.New(log, cfg, auth)
 
 	return &App{
-		GRPCSrv: grpcApp,
+		GRPCSrv:   grpcApp,
+		HTTPSrv:   httpApp,
+		broker:    broker,
+		db:        storage,
+		stopWatch: stopWatch,
 	}
 }
 
-func (a *App)Stop() {
+func (a *App) Stop() {
+	a.stopWatch()
 	a.GRPCSrv.Stop()
+	a.HTTPSrv.Stop()
+	a.broker.Stop()
+	a.db.Close()
+}
+
+// mustLoadKey returns nil if no key is configured.
+func mustLoadKey(cfg config.SigningConfig) *jwt.SigningKey {
+	if cfg.KeyPath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(cfg.KeyPath)
+	if err != nil {
+		panic(err)
+	}
+
+	key, err := jwt.ParsePrivateKey(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return &key
+}
+
+// mustLoadSecrets returns nil if no encryption key is configured.
+func mustLoadSecrets(key string) *secretbox.Box {
+	if key == "" {
+		return nil
+	}
+
+	box, err := secretbox.New(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return box
+}
+
+func mustLoadPasskeys(cfg config.WebAuthnConfig) *passkey.WebAuthn {
+	passkeys, err := passkey.New(passkey.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeout:       cfg.ChallengeTTL,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return passkeys
+}
+
+// mustLoadPoW returns nil if no key is configured.
+// Postgres backend shares used challenges between instances.
+func mustLoadPoW(cfg config.PoWConfig, storage *sqlstorage.Storage) *pow.Issuer {
+	if cfg.Key == "" {
+		return nil
+	}
+
+	var used pow.Backend
+	switch cfg.Backend {
+	case "postgres":
+		used = storage
+	case "memory", "":
+		used = pow.NewMemory()
+	default:
+		panic("invalid pow backend: " + cfg.Backend)
+	}
+
+	issuer, err := pow.New(pow.Config{
+		Key:           cfg.Key,
+		TTL:           cfg.TTL,
+		Difficulty:    cfg.Difficulty,
+		MaxDifficulty: cfg.MaxDifficulty,
+		Threshold:     cfg.Threshold,
+		Window:        cfg.Window,
+	}, used)
+	if err != nil {
+		panic(err)
+	}
+
+	return issuer
+}
+
+// mustLoadHasher hashes with configured algorithm and verifies
+// hashes of all known ones.
+func mustLoadHasher(cfg config.PasswordConfig) *passhash.Set {
+	hashers := []passhash.Hasher{
+		passhash.Argon2id{
+			Memory:  uint32(cfg.Argon2Memory),
+			Time:    uint32(cfg.Argon2Time),
+			Threads: uint8(cfg.Argon2Threads),
+			SaltLen: 16,
+			KeyLen:  32,
+		},
+		passhash.Scrypt{
+			LogN:    cfg.ScryptLogN,
+			R:       cfg.ScryptR,
+			P:       cfg.ScryptP,
+			SaltLen: 16,
+			KeyLen:  32,
+		},
+		passhash.Bcrypt{Cost: cfg.BcryptCost},
+	}
+
+	for _, h := range hashers {
+		if h.ID() == cfg.Algorithm {
+			return passhash.NewSet(h, hashers...)
+		}
+	}
+
+	panic("invalid password algorithm: " + cfg.Algorithm)
+}
+
+func passwordPolicy(cfg config.PasswordPolicyConfig) passpolicy.Policy {
+	return passpolicy.Policy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireLower:  cfg.RequireLower,
+		RequireUpper:  cfg.RequireUpper,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		BanUserInputs: cfg.BanUserInputs,
+		BannedWords:   cfg.BannedWords,
+		MinEntropy:    cfg.MinEntropy,
+	}
+}
+
+// mustLoadBreached returns nil if no corpus is configured.
+func mustLoadBreached(cfg config.BreachedConfig) pwned.Checker {
+	if cfg.Path == "" {
+		return nil
+	}
+
+	checker, err := pwned.Load(cfg.Path)
+	if err != nil {
+		panic(err)
+	}
+
+	return checker
+}
+
+// rateLimiter returns backend of rate limits, postgres one is shared by instances.
+func rateLimiter(cfg config.RateLimitConfig, storage *sqlstorage.Storage) ratelimit.Backend {
+	switch cfg.Backend {
+	case "postgres":
+		return storage
+	case "memory", "":
+		return ratelimit.NewMemory()
+	default:
+		panic("invalid rate limit backend: " + cfg.Backend)
+	}
 }
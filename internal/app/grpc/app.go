@@ -5,67 +5,32 @@ import (
 	"log/slog"
 	"net"
 
-	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
 	authgrpc "github.com/rautaruukkipalich/go_auth_grpc/internal/grpc/auth"
-	authsrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/auth"
-	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage/sqlstorage"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/ratelimit"
 	"google.golang.org/grpc"
 )
 
-type DBCloser interface {
-	Close()
-}
-
 type App struct {
 	log        *slog.Logger
 	gRPCServer *grpc.Server
-	broker     kafka.Brokerer
 	port       string
-	db         DBCloser
 }
 
 func New(
 	log *slog.Logger,
 	cfg *config.Config,
-	broker kafka.Brokerer,
+	auth authgrpc.Auth,
+	limiter ratelimit.Backend,
 ) *App {
 	gRPCServer := grpc.NewServer(
 		grpc.ConnectionTimeout(
 			cfg.Server.ConnTimeout,
 		),
-	)
-
-	var dbURI string
-
-	switch cfg.Database.Driver {
-	case "postgres":
-		dbURI = fmt.Sprintf(
-			"%s://%s:%s@%s:%s/%s?sslmode=disable",
-			cfg.Database.Driver,
-			cfg.Database.User,
-			cfg.Database.Password,
-			cfg.Database.Host,
-			cfg.Database.Port,
-			cfg.Database.DBName,
-		)
-	default:
-		panic("invalid database driver")
-	}
-
-	storage, err := sqlstorage.New(dbURI)
-	if err != nil {
-		panic(err)
-	}
-
-	auth := authsrvcs.New(
-		storage,
-		storage,
-		storage,
-		storage,
-		log,
-		cfg.Token.TTL,
-		broker,
+		grpc.ChainUnaryInterceptor(
+			UnaryRateLimitInterceptor(log, limiter, cfg.RateLimit.Methods),
+			authgrpc.UnaryAuthInterceptor(log, auth),
+		),
 	)
 
 	authgrpc.RegisterServer(gRPCServer, auth)
@@ -74,8 +39,6 @@ func New(
 		log:        log,
 		gRPCServer: gRPCServer,
 		port:       cfg.Server.Port,
-		broker:     broker,
-		db:         storage,
 	}
 }
 
@@ -109,7 +72,5 @@ func (a *App) Stop() {
 
 	log.Info("stop grpc server", slog.String("port", a.port))
 
-	a.broker.Stop()
-	a.db.Close()
 	a.gRPCServer.GracefulStop()
 }
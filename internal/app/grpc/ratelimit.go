@@ -0,0 +1,146 @@
+package grpcapp
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
+	authgrpc "github.com/rautaruukkipalich/go_auth_grpc/internal/grpc/auth"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/ratelimit"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	rateLimitLimitTrailer     = "x-ratelimit-limit"
+	rateLimitRemainingTrailer = "x-ratelimit-remaining"
+	retryAfterTrailer         = "retry-after"
+)
+
+type emailGetter interface {
+	GetEmail() string
+}
+
+type appIDGetter interface {
+	GetAppId() int32
+}
+
+// UnaryRateLimitInterceptor limits calls of methods configured in rules,
+// keyed by client ip, email and app id of request. Remaining quota of
+// the most exhausted bucket is sent in trailers. Requests pass if backend fails.
+func UnaryRateLimitInterceptor(
+	log *slog.Logger,
+	backend ratelimit.Backend,
+	rules map[string]config.RateLimitRule,
+) grpc.UnaryServerInterceptor {
+	for method, rule := range rules {
+		if rule.Burst <= 0 || rule.Period <= 0 {
+			panic("invalid rate limit of " + method)
+		}
+	}
+
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+
+		rule, ok := rules[method]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		limit := ratelimit.Limit{Burst: rule.Burst, Period: rule.Period}
+
+		var (
+			worst ratelimit.Result
+			taken bool
+		)
+		for _, key := range rateLimitKeys(ctx, req, method, rule.Keys) {
+			res, err := backend.TakeToken(ctx, key, limit)
+			if err != nil {
+				log.Error(
+					"failed to take rate limit token",
+					slog.String("method", info.FullMethod),
+					slerr.Err(err),
+				)
+				continue
+			}
+
+			if !taken || moreExhausted(res, worst) {
+				worst = res
+			}
+			taken = true
+		}
+
+		if !taken {
+			return handler(ctx, req)
+		}
+
+		trailer := metadata.Pairs(
+			rateLimitLimitTrailer, strconv.Itoa(worst.Limit),
+			rateLimitRemainingTrailer, strconv.Itoa(worst.Remaining),
+		)
+
+		if !worst.Allowed {
+			seconds := int(worst.RetryAfter.Seconds() + 0.999)
+			trailer.Set(retryAfterTrailer, strconv.Itoa(seconds))
+			grpc.SetTrailer(ctx, trailer)
+
+			log.Warn(
+				"rate limit exceeded",
+				slog.String("method", info.FullMethod),
+			)
+			return nil, authgrpc.RateLimitedStatus(worst.RetryAfter)
+		}
+
+		grpc.SetTrailer(ctx, trailer)
+
+		return handler(ctx, req)
+	}
+}
+
+// moreExhausted reports whether a is rejected for longer
+// or has less remaining quota than b.
+func moreExhausted(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// rateLimitKeys returns bucket keys of request, empty values are skipped.
+func rateLimitKeys(ctx context.Context, req any, method string, kinds []string) []string {
+	keys := make([]string, 0, len(kinds))
+
+	for _, kind := range kinds {
+		var value string
+
+		switch kind {
+		case "ip":
+			value = authgrpc.PeerIP(ctx)
+		case "email":
+			if r, ok := req.(emailGetter); ok {
+				value = strings.ToLower(r.GetEmail())
+			}
+		case "app_id":
+			if r, ok := req.(appIDGetter); ok && r.GetAppId() != 0 {
+				value = strconv.Itoa(int(r.GetAppId()))
+			}
+		}
+
+		if value != "" {
+			keys = append(keys, method+":"+kind+":"+value)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,70 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
+	jwkshttp "github.com/rautaruukkipalich/go_auth_grpc/internal/http/jwks"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       string
+}
+
+func New(
+	log *slog.Logger,
+	cfg *config.Config,
+	provider jwkshttp.JWKSProvider,
+) *App {
+	mux := http.NewServeMux()
+
+	jwkshttp.RegisterHandler(mux, log, provider)
+
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.HTTPServer.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.HTTPServer.Timeout,
+		ReadTimeout:       cfg.HTTPServer.Timeout,
+		WriteTimeout:      cfg.HTTPServer.Timeout,
+	}
+
+	return &App{
+		log:        log,
+		httpServer: httpServer,
+		port:       cfg.HTTPServer.Port,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "app.http.app.Run"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("run http server", slog.String("addr", a.httpServer.Addr))
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "app.http.app.Stop"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("stop http server", slog.String("port", a.port))
+
+	a.httpServer.Shutdown(context.Background())
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"time"
 
@@ -9,10 +10,21 @@ import (
 )
 
 type Config struct {
-	Env      string         `yaml:"env"`
-	Database DatabaseConfig `yaml:"database" env_required:"true"`
-	Server   ServerConfig   `yaml:"server" env_required:"true"`
-	Token    TokenConfig    `yaml:"token" env_required:"true"`
+	Env        string           `yaml:"env"`
+	Database   DatabaseConfig   `yaml:"database" env_required:"true"`
+	Server     ServerConfig     `yaml:"server" env_required:"true"`
+	HTTPServer HTTPServerConfig `yaml:"http_server"`
+	Token      TokenConfig      `yaml:"token" env_required:"true"`
+	Signing    SigningConfig    `yaml:"signing"`
+	MFA        MFAConfig        `yaml:"mfa"`
+	WebAuthn   WebAuthnConfig   `yaml:"webauthn"`
+	Lockout    LockoutConfig    `yaml:"lockout"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	PoW        PoWConfig        `yaml:"pow"`
+	Password   PasswordConfig   `yaml:"password"`
+	// PasswordPolicy is default policy, apps can override its rules.
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy"`
+	Breached       BreachedConfig       `yaml:"breached_passwords"`
 }
 
 type DatabaseConfig struct {
@@ -24,14 +36,188 @@ type DatabaseConfig struct {
 	DBName   string `yaml:"db_name"`
 }
 
+// URI returns connection string, it is empty for unknown driver.
+func (c DatabaseConfig) URI() string {
+	switch c.Driver {
+	case "postgres":
+		return fmt.Sprintf(
+			"%s://%s:%s@%s:%s/%s?sslmode=disable",
+			c.Driver,
+			c.User,
+			c.Password,
+			c.Host,
+			c.Port,
+			c.DBName,
+		)
+	default:
+		return ""
+	}
+}
+
 type ServerConfig struct {
 	Host        string        `yaml:"host"`
 	Port        string        `yaml:"port"`
 	ConnTimeout time.Duration `yaml:"conn_timeout"`
 }
 
+// HTTPServerConfig is used to publish JWKS.
+type HTTPServerConfig struct {
+	Port    string        `yaml:"port" env-default:"8002"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
 type TokenConfig struct {
-	TTL time.Duration `yaml:"ttl"`
+	TTL        time.Duration `yaml:"ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	// RevokedCacheTTL is how long "token is not revoked" answer is cached.
+	// Tokens revoked on other instances are accepted here until it expires.
+	RevokedCacheTTL time.Duration `yaml:"revoked_cache_ttl" env-default:"30s"`
+	// IntrospectionCacheTTL is how long introspection result is cached.
+	IntrospectionCacheTTL time.Duration `yaml:"introspection_cache_ttl" env-default:"10s"`
+	// PasswordResetTTL is lifetime of token sent by RequestPasswordReset.
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" env-default:"15m"`
+	// EmailVerificationTTL is lifetime of token sent on registration.
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl" env-default:"24h"`
+	// EmailChangeTTL is lifetime of token sent to new email by ChangeEmail.
+	EmailChangeTTL time.Duration `yaml:"email_change_ttl" env-default:"1h"`
+	// LoginCodeTTL and LoginCodeAttempts limit code sent by RequestLoginCode.
+	LoginCodeTTL      time.Duration `yaml:"login_code_ttl" env-default:"10m"`
+	LoginCodeAttempts int           `yaml:"login_code_attempts" env-default:"5"`
+	// PasswordChangeTTL is lifetime of token returned by login with expired password.
+	PasswordChangeTTL time.Duration `yaml:"password_change_ttl" env-default:"10m"`
+}
+
+type SigningConfig struct {
+	// KeyPath is PEM private key (RSA, ECDSA P-256 or Ed25519).
+	// It signs tokens until there is an active key in signing_keys table.
+	// Tokens are signed with app secret (HS256) if there are no keys at all.
+	KeyPath string `yaml:"key_path"`
+	// Algorithm of keys generated by authctl: RS256, ES256 or EdDSA.
+	Algorithm      string        `yaml:"algorithm" env-default:"EdDSA"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+	// EncryptionKey is base64 encoded 32 bytes key of private keys
+	// in signing_keys table. Keys can't be added without it.
+	EncryptionKey string `yaml:"encryption_key" env:"SIGNING_ENCRYPTION_KEY"`
+}
+
+// ActivationDelay is how long new signing key is pending: every instance
+// reloads it and JWKS caches of downstream services expire.
+func (c SigningConfig) ActivationDelay(jwksMaxAge time.Duration) time.Duration {
+	return c.ReloadInterval + jwksMaxAge
+}
+
+type MFAConfig struct {
+	// EncryptionKey is base64 encoded 32 bytes key of TOTP secrets.
+	// TOTP enrollment is disabled without it.
+	EncryptionKey string `yaml:"encryption_key" env:"MFA_ENCRYPTION_KEY"`
+	// Issuer is shown in authenticator apps.
+	Issuer string `yaml:"issuer" env-default:"go_auth_grpc"`
+	// ChallengeTTL is lifetime of token returned by Login for VerifyMFA.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	// RecoveryCodes is number of recovery codes generated at enrollment.
+	RecoveryCodes int `yaml:"recovery_codes" env-default:"10"`
+}
+
+// WebAuthnConfig describes relying party of passkeys.
+type WebAuthnConfig struct {
+	// RPID is domain passkeys are bound to, it can't be changed later.
+	RPID          string   `yaml:"rp_id" env-default:"localhost"`
+	RPDisplayName string   `yaml:"rp_display_name" env-default:"go_auth_grpc"`
+	RPOrigins     []string `yaml:"rp_origins" env-default:"http://localhost:3000"`
+	// ChallengeTTL is how long registration or login can be finished.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+}
+
+// LockoutConfig limits failed logins per user and per client IP.
+// Each failure after the first one delays next login, delay doubles
+// from BaseDelay up to MaxDelay. Reaching threshold locks login for Duration.
+type LockoutConfig struct {
+	Threshold int `yaml:"threshold" env-default:"5"`
+	// IPThreshold is higher as users behind NAT share one IP.
+	IPThreshold int           `yaml:"ip_threshold" env-default:"20"`
+	BaseDelay   time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay    time.Duration `yaml:"max_delay" env-default:"30s"`
+	Duration    time.Duration `yaml:"duration" env-default:"15m"`
+	// Window is how long failure is remembered.
+	Window time.Duration `yaml:"window" env-default:"1h"`
+}
+
+type RateLimitConfig struct {
+	// Backend is "memory" or "postgres", the latter shares limits between instances.
+	Backend string `yaml:"backend" env-default:"memory"`
+	// Methods are limits by RPC method name, e.g. "Login".
+	// Methods missing here are not limited.
+	Methods map[string]RateLimitRule `yaml:"methods"`
+}
+
+// RateLimitRule is token bucket: Burst requests at once, then Burst per Period.
+type RateLimitRule struct {
+	Burst  int           `yaml:"burst"`
+	Period time.Duration `yaml:"period"`
+	// Keys are "ip", "email" and "app_id" of request, each one is limited separately.
+	Keys []string `yaml:"keys"`
+}
+
+// PoWConfig is proof of work required by Register and password reset.
+type PoWConfig struct {
+	// Key is base64 encoded 32 bytes HMAC key of challenges.
+	// Proof of work is not required without it.
+	Key string        `yaml:"key" env:"POW_KEY"`
+	TTL time.Duration `yaml:"ttl" env-default:"2m"`
+	// Difficulty is number of leading zero bits of solution. It grows by one
+	// each time rate of challenges doubles over Threshold per Window.
+	Difficulty    int           `yaml:"difficulty" env-default:"18"`
+	MaxDifficulty int           `yaml:"max_difficulty" env-default:"24"`
+	Threshold     int           `yaml:"threshold" env-default:"60"`
+	Window        time.Duration `yaml:"window" env-default:"1m"`
+	// Backend is "memory" or "postgres", the latter rejects challenges
+	// used on other instances too.
+	Backend string `yaml:"backend" env-default:"memory"`
+}
+
+// PasswordConfig is hashing of passwords. Hashes made by other algorithm
+// or with other parameters are replaced on successful login.
+type PasswordConfig struct {
+	// Algorithm of new hashes: argon2id, scrypt or bcrypt.
+	Algorithm  string `yaml:"algorithm" env-default:"argon2id"`
+	BcryptCost int    `yaml:"bcrypt_cost" env-default:"10"`
+	// Argon2Memory is in KiB.
+	Argon2Memory  int `yaml:"argon2_memory" env-default:"19456"`
+	Argon2Time    int `yaml:"argon2_time" env-default:"2"`
+	Argon2Threads int `yaml:"argon2_threads" env-default:"1"`
+	// ScryptLogN is log2 of scrypt cost N.
+	ScryptLogN int `yaml:"scrypt_log_n" env-default:"15"`
+	ScryptR    int `yaml:"scrypt_r" env-default:"8"`
+	ScryptP    int `yaml:"scrypt_p" env-default:"1"`
+	// History is number of previous passwords ChangePassword rejects
+	// besides current one, they are kept in password history.
+	History int `yaml:"history" env-default:"5"`
+	// ExpiryWarningDays is how long before password expiry user is warned
+	// by email. Expiry is set per app.
+	ExpiryWarningDays int `yaml:"expiry_warning_days" env-default:"7"`
+}
+
+// PasswordPolicyConfig is rules of new passwords.
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" env-default:"8"`
+	// MaxLength of zero is unlimited. Keep it at 72 for bcrypt.
+	MaxLength     int  `yaml:"max_length" env-default:"128"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	// BanUserInputs rejects passwords containing email or username.
+	BanUserInputs bool     `yaml:"ban_user_inputs" env-default:"true"`
+	BannedWords   []string `yaml:"banned_words"`
+	// MinEntropy is minimal estimated strength of password in bits.
+	MinEntropy float64 `yaml:"min_entropy" env-default:"30"`
+}
+
+// BreachedConfig is local corpus of breached passwords, new passwords
+// found in it are rejected. Path is SHA-1 hash list or bloom filter
+// built by cmd/pwnedbuild, check is off without it.
+type BreachedConfig struct {
+	Path string `yaml:"path" env:"BREACHED_PASSWORDS_PATH"`
 }
 
 func MustLoadConfig() *Config {
@@ -1,7 +1,18 @@
 package models
 
 type App struct {
-	ID int
-	Name string
+	ID     int
+	Name   string
 	Secret string
-}
\ No newline at end of file
+	// Scopes are granted to every token issued for the app.
+	Scopes []string
+	// RequireVerifiedEmail makes Login refuse users with unverified email.
+	RequireVerifiedEmail bool
+	// AllowLoginCode lets users log in with code sent by email, without password.
+	AllowLoginCode bool
+	// PasswordPolicy is JSON with rules overriding default password policy.
+	PasswordPolicy []byte
+	// PasswordMaxAgeDays is how long password of user is valid for the app,
+	// zero means it does not expire.
+	PasswordMaxAgeDays int
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LoginCode is numeric code sent by email for passwordless login to app.
+type LoginCode struct {
+	ID        int64
+	UserID    int32
+	AppID     int
+	CodeHash  []byte
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
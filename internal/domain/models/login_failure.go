@@ -0,0 +1,25 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LoginFailure counts failed logins of one subject: email or client IP.
+type LoginFailure struct {
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// EmailSubject is LoginFailure subject of email. Emails of unknown users
+// are counted the same way, so lockout does not tell whether user exists.
+func EmailSubject(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// IPSubject is LoginFailure subject of client IP.
+func IPSubject(ip string) string {
+	return "ip:" + ip
+}
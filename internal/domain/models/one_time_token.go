@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Purposes of one time tokens, token of one purpose can't be used for another.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+	PurposeEmailChange       = "email_change"
+	PurposeMFAChallenge      = "mfa_challenge"
+	PurposePasswordChange    = "password_change"
+)
+
+// OneTimeToken is a single use secret sent to user by email.
+// Only its hash is stored.
+type OneTimeToken struct {
+	ID      int64
+	UserID  int32
+	Purpose string
+	// Payload is purpose specific data, e.g. new email for email change.
+	Payload   string
+	TokenHash []byte
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Principal is the authenticated caller: owner of verified access token.
+type Principal struct {
+	User      User
+	AppID     int
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Scopes    []string
+	Roles     []string
+}
+
+// RoleAdmin is granted to users allowed to manage other accounts.
+const RoleAdmin = "admin"
+
+// HasRole reports whether principal's user has role.
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	// SigningKeyPending is published in JWKS but not used for signing yet.
+	SigningKeyPending = "pending"
+	// SigningKeyActive signs new tokens.
+	SigningKeyActive = "active"
+	// SigningKeyRetiring is only used to verify tokens issued before rotation.
+	SigningKeyRetiring = "retiring"
+	// SigningKeyRetired is not used at all.
+	SigningKeyRetired = "retired"
+)
+
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  []byte
+	State       string
+	CreatedAt   time.Time
+	ActivatedAt time.Time
+	RetiringAt  time.Time
+	RetiredAt   time.Time
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// MFAToken is returned by Login instead of tokens when user has MFA
+	// enabled. It is exchanged for tokens by VerifyMFA.
+	MFAToken string
+	// PasswordChangeToken is returned instead of tokens when password is
+	// expired. It is only accepted by ChangeExpiredPassword.
+	PasswordChangeToken string
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int32
+	AppID     int
+	FamilyID  string
+	TokenHash []byte
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+	CreatedAt time.Time
+}
+
+func (t RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+func (t RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t RefreshToken) IsExpired(now time.Time) bool {
+	return now.After(t.ExpiresAt)
+}
+
+// Introspection is RFC 7662 token introspection result.
+// Only Active is set for inactive token.
+type Introspection struct {
+	Active    bool
+	Sub       int
+	AppID     int
+	Username  string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+	Scopes    []string
+	Roles     []string
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TOTP is user's authenticator app enrollment.
+// It is pending until the first code is confirmed.
+type TOTP struct {
+	UserID int32
+	// Secret is encrypted, see secretbox.
+	Secret       []byte
+	LastUsedStep int64
+	EnabledAt    time.Time
+	CreatedAt    time.Time
+}
+
+func (t TOTP) IsEnabled() bool {
+	return !t.EnabledAt.IsZero()
+}
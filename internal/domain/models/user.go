@@ -8,7 +8,13 @@ type User struct {
 	Username           string
 	Slug               string
 	HashedPass         []byte
+	Roles              []string
+	EmailVerifiedAt    time.Time
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	LastPasswordChange time.Time
 }
+
+func (u User) IsEmailVerified() bool {
+	return !u.EmailVerifiedAt.IsZero()
+}
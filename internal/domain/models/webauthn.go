@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WebAuthnCredential is passkey registered by user.
+type WebAuthnCredential struct {
+	ID              int64
+	UserID          int32
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      time.Time
+}
+
+// WebAuthnSession is server side state of registration or login ceremony.
+// UserID is zero for login, user is found by passkey.
+type WebAuthnSession struct {
+	IDHash    []byte
+	UserID    int32
+	AppID     int
+	Data      []byte
+	ExpiresAt time.Time
+}
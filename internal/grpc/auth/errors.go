@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authsrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/auth"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is ErrorInfo domain, reasons are unique within it.
+const errorDomain = "auth.rautaruukkipalich"
+
+const (
+	ReasonInvalidArgument       = "INVALID_ARGUMENT"
+	ReasonInvalidCredentials    = "INVALID_CREDENTIALS"
+	ReasonInvalidAppCredentials = "INVALID_APP_CREDENTIALS"
+	ReasonTokenMissing          = "TOKEN_MISSING"
+	ReasonTokenInvalid          = "TOKEN_INVALID"
+	ReasonTokenExpired          = "TOKEN_EXPIRED"
+	ReasonTokenRevoked          = "TOKEN_REVOKED"
+	ReasonTokenReused           = "TOKEN_REUSED"
+	ReasonUserExists            = "USER_EXISTS"
+	ReasonUserNotFound          = "USER_NOT_FOUND"
+	ReasonAppNotFound           = "APP_NOT_FOUND"
+	ReasonPermissionDenied      = "PERMISSION_DENIED"
+	ReasonTooManyAttempts       = "TOO_MANY_ATTEMPTS"
+	ReasonEmailNotVerified      = "EMAIL_NOT_VERIFIED"
+	ReasonMFANotConfigured      = "MFA_NOT_CONFIGURED"
+	ReasonMFAAlreadyEnabled     = "MFA_ALREADY_ENABLED"
+	ReasonMFANotEnrolled        = "MFA_NOT_ENROLLED"
+	ReasonPasskeyExists         = "PASSKEY_EXISTS"
+	ReasonLoginCodeNotAllowed   = "LOGIN_CODE_NOT_ALLOWED"
+	ReasonRateLimited           = "RATE_LIMITED"
+	ReasonChallengeRequired     = "CHALLENGE_REQUIRED"
+	ReasonChallengeFailed       = "CHALLENGE_FAILED"
+	ReasonWeakPassword          = "WEAK_PASSWORD"
+)
+
+type errorMapping struct {
+	target error
+	code   codes.Code
+	reason string
+	msg    string
+}
+
+// errorMappings is checked in order, so more specific errors go first.
+var errorMappings = []errorMapping{
+	{jwt.ErrTokenExpired, codes.Unauthenticated, ReasonTokenExpired, "token expired"},
+	{authsrvcs.ErrTokenRevoked, codes.Unauthenticated, ReasonTokenRevoked, "token revoked"},
+	{authsrvcs.ErrTokenReused, codes.Unauthenticated, ReasonTokenReused, "token reused"},
+	{authsrvcs.ErrInvalidToken, codes.Unauthenticated, ReasonTokenInvalid, "invalid token"},
+	{authsrvcs.ErrInvalidCredentials, codes.Unauthenticated, ReasonInvalidCredentials, "invalid credentials"},
+	{authsrvcs.ErrInvalidAppCredentials, codes.Unauthenticated, ReasonInvalidAppCredentials, "invalid app credentials"},
+	{authsrvcs.ErrPermissionDenied, codes.PermissionDenied, ReasonPermissionDenied, "permission denied"},
+	{authsrvcs.ErrTooManyAttempts, codes.ResourceExhausted, ReasonTooManyAttempts, "too many attempts"},
+	{authsrvcs.ErrEmailNotVerified, codes.FailedPrecondition, ReasonEmailNotVerified, "email is not verified"},
+	{authsrvcs.ErrMFANotConfigured, codes.Unimplemented, ReasonMFANotConfigured, "mfa is not available"},
+	{authsrvcs.ErrMFAAlreadyEnabled, codes.FailedPrecondition, ReasonMFAAlreadyEnabled, "mfa is already enabled"},
+	{authsrvcs.ErrMFANotEnrolled, codes.FailedPrecondition, ReasonMFANotEnrolled, "totp enrollment is not started"},
+	{authsrvcs.ErrPasskeyExist, codes.AlreadyExists, ReasonPasskeyExists, "passkey is already registered"},
+	{authsrvcs.ErrLoginCodeNotAllowed, codes.PermissionDenied, ReasonLoginCodeNotAllowed, "login code is not allowed for app"},
+	{authsrvcs.ErrChallengeRequired, codes.FailedPrecondition, ReasonChallengeRequired, "proof of work is required"},
+	{authsrvcs.ErrChallengeFailed, codes.FailedPrecondition, ReasonChallengeFailed, "proof of work is not valid"},
+	{authsrvcs.ErrUserExist, codes.AlreadyExists, ReasonUserExists, "user already exists"},
+	{storage.ErrUserExist, codes.AlreadyExists, ReasonUserExists, "user already exists"},
+	{storage.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{storage.ErrAppNotFound, codes.NotFound, ReasonAppNotFound, "app not found"},
+}
+
+// toStatus maps errors of service and storage layers to gRPC status.
+// Unknown errors become Internal without details, so nothing leaks to client.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	var policy *authsrvcs.PasswordPolicyError
+	if errors.As(err, &policy) {
+		details := make([]*errdetails.BadRequest_FieldViolation, 0, len(policy.Violations))
+		for _, v := range policy.Violations {
+			details = append(details, &errdetails.BadRequest_FieldViolation{
+				Field:       policy.Field,
+				Description: v.Description,
+			})
+		}
+		return newStatus(codes.InvalidArgument, ReasonWeakPassword, "password does not match policy", details...)
+	}
+
+	var locked *authsrvcs.LockedError
+	if errors.As(err, &locked) {
+		return retryStatus(ReasonTooManyAttempts, "too many attempts", locked.RetryAfter)
+	}
+
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			return newStatus(m.code, m.reason, m.msg)
+		}
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func newStatus(code codes.Code, reason, msg string, details ...*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(code, msg)
+
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	if len(details) > 0 {
+		withBadRequest, err := withDetails.WithDetails(&errdetails.BadRequest{
+			FieldViolations: details,
+		})
+		if err == nil {
+			withDetails = withBadRequest
+		}
+	}
+
+	return withDetails.Err()
+}
+
+// RateLimitedStatus is returned by rate limiter when request is rejected.
+func RateLimitedStatus(delay time.Duration) error {
+	return retryStatus(ReasonRateLimited, "rate limit exceeded", delay)
+}
+
+// retryStatus is ResourceExhausted status telling client when to retry.
+func retryStatus(reason, msg string, delay time.Duration) error {
+	st := status.Convert(newStatus(codes.ResourceExhausted, reason, msg))
+
+	withRetry, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(delay),
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withRetry.Err()
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (principal models.Principal, err error)
+}
+
+type principalKey struct{}
+
+// protectedMethods require access token. Other methods ignore
+// "authorization" header, e.g. expired access token sent with Refresh.
+var protectedMethods = map[string]bool{
+	"ChangePassword":            true,
+	"ChangeUsername":            true,
+	"ChangeEmail":               true,
+	"Logout":                    true,
+	"Me":                        true,
+	"BeginTOTPEnrollment":       true,
+	"ConfirmTOTPEnrollment":     true,
+	"RegenerateRecoveryCodes":   true,
+	"BeginPasskeyRegistration":  true,
+	"FinishPasskeyRegistration": true,
+	"UnlockAccount":             true,
+}
+
+// UnaryAuthInterceptor verifies bearer token from "authorization" metadata
+// of protected methods and stores its owner in context. Requests without
+// the header pass as is, handlers decide whether authentication is required.
+func UnaryAuthInterceptor(log *slog.Logger, auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		if !protectedMethods[method] {
+			return handler(ctx, req)
+		}
+
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		principal, err := auth.Authenticate(ctx, token)
+		if err != nil {
+			log.Warn(
+				"failed to authenticate",
+				slog.String("method", info.FullMethod),
+				slerr.Err(err),
+			)
+			return nil, toStatus(err)
+		}
+
+		return handler(context.WithValue(ctx, principalKey{}, principal), req)
+	}
+}
+
+// PrincipalFromContext returns principal stored by UnaryAuthInterceptor.
+func PrincipalFromContext(ctx context.Context) (models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(models.Principal)
+	return principal, ok
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	if len(values[0]) <= len(bearerPrefix) || !strings.EqualFold(values[0][:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(values[0][len(bearerPrefix):]), true
+}
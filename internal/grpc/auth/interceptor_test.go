@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	authsrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type rejectingAuthenticator struct{}
+
+func (rejectingAuthenticator) Authenticate(ctx context.Context, token string) (models.Principal, error) {
+	return models.Principal{}, authsrvcs.ErrInvalidToken
+}
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	interceptor := UnaryAuthInterceptor(slog.New(slog.NewTextHandler(io.Discard, nil)), rejectingAuthenticator{})
+
+	tests := []struct {
+		method   string
+		wantCode codes.Code
+	}{
+		{"/AuthService/Refresh", codes.OK},
+		{"/AuthService/Login", codes.OK},
+		{"/AuthService/Introspect", codes.OK},
+		{"/AuthService/Me", codes.Unauthenticated},
+		{"/AuthService/ChangePassword", codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(
+				context.Background(),
+				metadata.Pairs(authorizationHeader, "Bearer expired"),
+			)
+
+			handler := func(ctx context.Context, req any) (any, error) {
+				if _, ok := PrincipalFromContext(ctx); ok {
+					return nil, errors.New("principal is set")
+				}
+				return "ok", nil
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %s, want %s (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
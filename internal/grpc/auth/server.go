@@ -4,19 +4,45 @@ import (
 	"context"
 
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pow"
 	auth_grpc "github.com/rautaruukkipalich/go_auth_grpc_contract/gen/go/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type Auth interface {
-	Register(ctx context.Context, email, username, password string) (success bool, err error)
-	Login(ctx context.Context, email, password string, appID int) (token string, err error)
-	ChangeUsername(ctx context.Context, token, username string) (success bool, err error)
-	ChangePassword(ctx context.Context, token, newPassword string) (success bool, err error)
-	ResetPassword(ctx context.Context, email string) (success bool, err error)
-	Me(ctx context.Context, token string) (user models.User, err error)
+	Authenticator
+	Register(ctx context.Context, email, username, password string, appID int) (success bool, err error)
+	Login(ctx context.Context, email, password string, appID int, ip string) (tokens models.TokenPair, err error)
+	ChangeUsername(ctx context.Context, principal models.Principal, username string) (success bool, err error)
+	ChangePassword(ctx context.Context, principal models.Principal, newPassword string) (success bool, err error)
+	ChangeExpiredPassword(ctx context.Context, token, newPassword string) (success bool, err error)
+	RequestPasswordReset(ctx context.Context, email string, appID int) (success bool, err error)
+	ConfirmPasswordReset(ctx context.Context, token, newPassword string) (success bool, err error)
+	VerifyEmail(ctx context.Context, token string) (success bool, err error)
+	ResendVerification(ctx context.Context, email string) (success bool, err error)
+	ChangeEmail(ctx context.Context, principal models.Principal, email string) (success bool, err error)
+	ConfirmEmailChange(ctx context.Context, token string) (success bool, err error)
+	BeginTOTPEnrollment(ctx context.Context, principal models.Principal) (secret, uri string, err error)
+	ConfirmTOTPEnrollment(ctx context.Context, principal models.Principal, code string) (recoveryCodes []string, err error)
+	RegenerateRecoveryCodes(ctx context.Context, principal models.Principal) (recoveryCodes []string, err error)
+	BeginPasskeyRegistration(ctx context.Context, principal models.Principal) (sessionID string, options []byte, err error)
+	FinishPasskeyRegistration(ctx context.Context, principal models.Principal, sessionID string, credential []byte) (success bool, err error)
+	BeginPasskeyLogin(ctx context.Context, appID int) (sessionID string, options []byte, err error)
+	FinishPasskeyLogin(ctx context.Context, sessionID string, credential []byte) (tokens models.TokenPair, err error)
+	VerifyMFA(ctx context.Context, mfaToken, code string) (tokens models.TokenPair, err error)
+	RequestLoginCode(ctx context.Context, email string, appID int) (success bool, err error)
+	LoginWithCode(ctx context.Context, email string, appID int, code, ip string) (tokens models.TokenPair, err error)
+	UnlockAccount(ctx context.Context, principal models.Principal, email string) (success bool, err error)
+	Me(ctx context.Context, principal models.Principal) (user models.User, err error)
+	Refresh(ctx context.Context, refreshToken string) (tokens models.TokenPair, err error)
+	Logout(ctx context.Context, principal models.Principal, refreshToken string) (success bool, err error)
+	RevokeToken(ctx context.Context, token string) (success bool, err error)
+	GetJWKS(ctx context.Context) (jwks jwt.JWKS, err error)
+	GetChallenge(ctx context.Context) (challenge pow.Challenge, err error)
+	CheckChallenge(ctx context.Context, challenge, nonce string) (err error)
+	Introspect(ctx context.Context, appID int, appSecret, token string) (res models.Introspection, err error)
 }
 
 type serverAPI struct {
@@ -31,18 +57,44 @@ func RegisterServer(gRPC *grpc.Server, auth Auth) {
 	)
 }
 
+// principal returns caller authenticated by UnaryAuthInterceptor.
+// Token from request body is a deprecated fallback for old clients.
+func (s *serverAPI) principal(ctx context.Context, bodyToken string) (models.Principal, error) {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal, nil
+	}
+
+	if bodyToken == "" {
+		return models.Principal{}, newStatus(codes.Unauthenticated, ReasonTokenMissing, "missing bearer token")
+	}
+
+	if err := validateBodyToken(bodyToken); err != nil {
+		return models.Principal{}, err
+	}
+
+	principal, err := s.auth.Authenticate(ctx, bodyToken)
+	if err != nil {
+		return models.Principal{}, toStatus(err)
+	}
+
+	return principal, nil
+}
+
 func (s *serverAPI) Register(
 	ctx context.Context,
 	req *auth_grpc.RegisterRequest,
 ) (*auth_grpc.RegisterResponse, error) {
-	
-	if err := validateRegister(req.GetEmail(), req.GetPassword(), req.GetUsername()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if err := validateRegister(req.GetEmail(), req.GetUsername(), req.GetPassword(), req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.CheckChallenge(ctx, req.GetChallenge(), req.GetNonce()); err != nil {
+		return nil, toStatus(err)
 	}
 
-	success, err := s.auth.Register(ctx, req.GetEmail(), req.GetUsername(), req.GetPassword())
+	success, err := s.auth.Register(ctx, req.GetEmail(), req.GetUsername(), req.GetPassword(), int(req.GetAppId()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.RegisterResponse{
@@ -54,19 +106,23 @@ func (s *serverAPI) Login(
 	ctx context.Context,
 	req *auth_grpc.LoginRequest,
 ) (*auth_grpc.LoginResponse, error) {
-	
 	if err := validateLogin(req.GetEmail(), req.GetPassword(), req.GetAppId()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, err
 	}
 
 	// TODO: change app id get from req
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId()))
+	tokens, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId()), PeerIP(ctx))
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.LoginResponse{
-		Token: token,
+		Token:               tokens.AccessToken,
+		RefreshToken:        tokens.RefreshToken,
+		MfaRequired:         tokens.MFAToken != "",
+		MfaToken:            tokens.MFAToken,
+		PasswordExpired:     tokens.PasswordChangeToken != "",
+		PasswordChangeToken: tokens.PasswordChangeToken,
 	}, nil
 }
 
@@ -74,13 +130,34 @@ func (s *serverAPI) ChangePassword(
 	ctx context.Context,
 	req *auth_grpc.ChangePasswordRequest,
 ) (*auth_grpc.ChangePasswordResponse, error) {
-	if err := validateChangePassword(req.GetToken(), req.GetNewPassword()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if err := validateChangePassword(req.GetNewPassword()); err != nil {
+		return nil, err
+	}
+
+	// token of login with expired password replaces access token
+	if req.GetPasswordChangeToken() != "" {
+		if err := validateChangeExpiredPassword(req.GetPasswordChangeToken()); err != nil {
+			return nil, err
+		}
+
+		success, err := s.auth.ChangeExpiredPassword(ctx, req.GetPasswordChangeToken(), req.GetNewPassword())
+		if err != nil {
+			return nil, toStatus(err)
+		}
+
+		return &auth_grpc.ChangePasswordResponse{
+			Success: success,
+		}, nil
 	}
 
-	success, err := s.auth.ChangePassword(ctx, req.GetToken(), req.GetNewPassword())
+	principal, err := s.principal(ctx, req.GetToken())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, err
+	}
+
+	success, err := s.auth.ChangePassword(ctx, principal, req.GetNewPassword())
+	if err != nil {
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.ChangePasswordResponse{
@@ -92,13 +169,18 @@ func (s *serverAPI) ChangeUsername(
 	ctx context.Context,
 	req *auth_grpc.ChangeUsernameRequest,
 ) (*auth_grpc.ChangeUsernameResponse, error) {
-	if err := validateChangeUsername(req.GetToken(), req.GetUsername()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if err := validateChangeUsername(req.GetUsername()); err != nil {
+		return nil, err
 	}
 
-	success, err := s.auth.ChangeUsername(ctx, req.GetToken(), req.GetUsername())
+	principal, err := s.principal(ctx, req.GetToken())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, err
+	}
+
+	success, err := s.auth.ChangeUsername(ctx, principal, req.GetUsername())
+	if err != nil {
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.ChangeUsernameResponse{
@@ -106,17 +188,22 @@ func (s *serverAPI) ChangeUsername(
 	}, nil
 }
 
+// ResetPassword is deprecated alias of RequestPasswordReset.
 func (s *serverAPI) ResetPassword(
 	ctx context.Context,
 	req *auth_grpc.ResetPasswordRequest,
 ) (*auth_grpc.ResetPasswordResponse, error) {
-	if err := validateResetPassword(req.GetEmail()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if err := validateResetPassword(req.GetEmail(), req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.CheckChallenge(ctx, req.GetChallenge(), req.GetNonce()); err != nil {
+		return nil, toStatus(err)
 	}
 
-	success, err := s.auth.ResetPassword(ctx, req.GetEmail())
+	success, err := s.auth.RequestPasswordReset(ctx, req.GetEmail(), int(req.GetAppId()))
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.ResetPasswordResponse{
@@ -124,17 +211,366 @@ func (s *serverAPI) ResetPassword(
 	}, nil
 }
 
+func (s *serverAPI) RequestPasswordReset(
+	ctx context.Context,
+	req *auth_grpc.RequestPasswordResetRequest,
+) (*auth_grpc.RequestPasswordResetResponse, error) {
+	if err := validateResetPassword(req.GetEmail(), req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.CheckChallenge(ctx, req.GetChallenge(), req.GetNonce()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	success, err := s.auth.RequestPasswordReset(ctx, req.GetEmail(), int(req.GetAppId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.RequestPasswordResetResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmPasswordReset(
+	ctx context.Context,
+	req *auth_grpc.ConfirmPasswordResetRequest,
+) (*auth_grpc.ConfirmPasswordResetResponse, error) {
+	if err := validateConfirmPasswordReset(req.GetToken(), req.GetNewPassword()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.ConfirmPasswordReset(ctx, req.GetToken(), req.GetNewPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.ConfirmPasswordResetResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) VerifyEmail(
+	ctx context.Context,
+	req *auth_grpc.VerifyEmailRequest,
+) (*auth_grpc.VerifyEmailResponse, error) {
+	if err := validateVerifyEmail(req.GetToken()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.VerifyEmail(ctx, req.GetToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.VerifyEmailResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) ResendVerification(
+	ctx context.Context,
+	req *auth_grpc.ResendVerificationRequest,
+) (*auth_grpc.ResendVerificationResponse, error) {
+	if err := validateResendVerification(req.GetEmail()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.ResendVerification(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.ResendVerificationResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) ChangeEmail(
+	ctx context.Context,
+	req *auth_grpc.ChangeEmailRequest,
+) (*auth_grpc.ChangeEmailResponse, error) {
+	if err := validateChangeEmail(req.GetEmail()); err != nil {
+		return nil, err
+	}
+
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.ChangeEmail(ctx, principal, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.ChangeEmailResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmEmailChange(
+	ctx context.Context,
+	req *auth_grpc.ConfirmEmailChangeRequest,
+) (*auth_grpc.ConfirmEmailChangeResponse, error) {
+	if err := validateConfirmEmailChange(req.GetToken()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.ConfirmEmailChange(ctx, req.GetToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.ConfirmEmailChangeResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) BeginTOTPEnrollment(
+	ctx context.Context,
+	req *auth_grpc.BeginTOTPEnrollmentRequest,
+) (*auth_grpc.BeginTOTPEnrollmentResponse, error) {
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	secret, uri, err := s.auth.BeginTOTPEnrollment(ctx, principal)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.BeginTOTPEnrollmentResponse{
+		Secret: secret,
+		Uri:    uri,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmTOTPEnrollment(
+	ctx context.Context,
+	req *auth_grpc.ConfirmTOTPEnrollmentRequest,
+) (*auth_grpc.ConfirmTOTPEnrollmentResponse, error) {
+	if err := validateConfirmTOTPEnrollment(req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.auth.ConfirmTOTPEnrollment(ctx, principal, req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.ConfirmTOTPEnrollmentResponse{
+		Success:       true,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *serverAPI) VerifyMFA(
+	ctx context.Context,
+	req *auth_grpc.VerifyMFARequest,
+) (*auth_grpc.VerifyMFAResponse, error) {
+	if err := validateVerifyMFA(req.GetMfaToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.VerifyMFA(ctx, req.GetMfaToken(), req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.VerifyMFAResponse{
+		Token:               tokens.AccessToken,
+		RefreshToken:        tokens.RefreshToken,
+		PasswordExpired:     tokens.PasswordChangeToken != "",
+		PasswordChangeToken: tokens.PasswordChangeToken,
+	}, nil
+}
+
+func (s *serverAPI) RegenerateRecoveryCodes(
+	ctx context.Context,
+	req *auth_grpc.RegenerateRecoveryCodesRequest,
+) (*auth_grpc.RegenerateRecoveryCodesResponse, error) {
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.auth.RegenerateRecoveryCodes(ctx, principal)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.RegenerateRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *serverAPI) BeginPasskeyRegistration(
+	ctx context.Context,
+	req *auth_grpc.BeginPasskeyRegistrationRequest,
+) (*auth_grpc.BeginPasskeyRegistrationResponse, error) {
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, options, err := s.auth.BeginPasskeyRegistration(ctx, principal)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.BeginPasskeyRegistrationResponse{
+		SessionId: sessionID,
+		Options:   options,
+	}, nil
+}
+
+func (s *serverAPI) FinishPasskeyRegistration(
+	ctx context.Context,
+	req *auth_grpc.FinishPasskeyRegistrationRequest,
+) (*auth_grpc.FinishPasskeyRegistrationResponse, error) {
+	if err := validatePasskeyCredential(req.GetSessionId(), req.GetCredential()); err != nil {
+		return nil, err
+	}
+
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.FinishPasskeyRegistration(ctx, principal, req.GetSessionId(), req.GetCredential())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.FinishPasskeyRegistrationResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) BeginPasskeyLogin(
+	ctx context.Context,
+	req *auth_grpc.BeginPasskeyLoginRequest,
+) (*auth_grpc.BeginPasskeyLoginResponse, error) {
+	if err := validateBeginPasskeyLogin(req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	sessionID, options, err := s.auth.BeginPasskeyLogin(ctx, int(req.GetAppId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.BeginPasskeyLoginResponse{
+		SessionId: sessionID,
+		Options:   options,
+	}, nil
+}
+
+func (s *serverAPI) FinishPasskeyLogin(
+	ctx context.Context,
+	req *auth_grpc.FinishPasskeyLoginRequest,
+) (*auth_grpc.FinishPasskeyLoginResponse, error) {
+	if err := validatePasskeyCredential(req.GetSessionId(), req.GetCredential()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.FinishPasskeyLogin(ctx, req.GetSessionId(), req.GetCredential())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.FinishPasskeyLoginResponse{
+		Token:               tokens.AccessToken,
+		RefreshToken:        tokens.RefreshToken,
+		PasswordExpired:     tokens.PasswordChangeToken != "",
+		PasswordChangeToken: tokens.PasswordChangeToken,
+	}, nil
+}
+
+func (s *serverAPI) RequestLoginCode(
+	ctx context.Context,
+	req *auth_grpc.RequestLoginCodeRequest,
+) (*auth_grpc.RequestLoginCodeResponse, error) {
+	if err := validateRequestLoginCode(req.GetEmail(), req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.RequestLoginCode(ctx, req.GetEmail(), int(req.GetAppId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.RequestLoginCodeResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) LoginWithCode(
+	ctx context.Context,
+	req *auth_grpc.LoginWithCodeRequest,
+) (*auth_grpc.LoginWithCodeResponse, error) {
+	if err := validateLoginWithCode(req.GetEmail(), req.GetAppId(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.LoginWithCode(ctx, req.GetEmail(), int(req.GetAppId()), req.GetCode(), PeerIP(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.LoginWithCodeResponse{
+		Token:               tokens.AccessToken,
+		RefreshToken:        tokens.RefreshToken,
+		MfaRequired:         tokens.MFAToken != "",
+		MfaToken:            tokens.MFAToken,
+		PasswordExpired:     tokens.PasswordChangeToken != "",
+		PasswordChangeToken: tokens.PasswordChangeToken,
+	}, nil
+}
+
+func (s *serverAPI) UnlockAccount(
+	ctx context.Context,
+	req *auth_grpc.UnlockAccountRequest,
+) (*auth_grpc.UnlockAccountResponse, error) {
+	if err := validateUnlockAccount(req.GetEmail()); err != nil {
+		return nil, err
+	}
+
+	principal, err := s.principal(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.UnlockAccount(ctx, principal, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.UnlockAccountResponse{
+		Success: success,
+	}, nil
+}
+
 func (s *serverAPI) Me(
 	ctx context.Context,
 	req *auth_grpc.MeRequest,
 ) (*auth_grpc.MeResponse, error) {
-	if err := validateMe(req.GetToken()); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	principal, err := s.principal(ctx, req.GetToken())
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.auth.Me(ctx, req.GetToken())
+	user, err := s.auth.Me(ctx, principal)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, toStatus(err)
 	}
 
 	return &auth_grpc.MeResponse{
@@ -145,3 +581,145 @@ func (s *serverAPI) Me(
 		},
 	}, nil
 }
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	req *auth_grpc.RefreshRequest,
+) (*auth_grpc.RefreshResponse, error) {
+	if err := validateRefresh(req.GetRefreshToken()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.RefreshResponse{
+		Token:               tokens.AccessToken,
+		RefreshToken:        tokens.RefreshToken,
+		PasswordExpired:     tokens.PasswordChangeToken != "",
+		PasswordChangeToken: tokens.PasswordChangeToken,
+	}, nil
+}
+
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	req *auth_grpc.LogoutRequest,
+) (*auth_grpc.LogoutResponse, error) {
+	principal, err := s.principal(ctx, req.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.Logout(ctx, principal, req.GetRefreshToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.LogoutResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) RevokeToken(
+	ctx context.Context,
+	req *auth_grpc.RevokeTokenRequest,
+) (*auth_grpc.RevokeTokenResponse, error) {
+	if err := validateRevokeToken(req.GetToken()); err != nil {
+		return nil, err
+	}
+
+	success, err := s.auth.RevokeToken(ctx, req.GetToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &auth_grpc.RevokeTokenResponse{
+		Success: success,
+	}, nil
+}
+
+func (s *serverAPI) GetChallenge(
+	ctx context.Context,
+	req *auth_grpc.GetChallengeRequest,
+) (*auth_grpc.GetChallengeResponse, error) {
+	challenge, err := s.auth.GetChallenge(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &auth_grpc.GetChallengeResponse{
+		Challenge:  challenge.Value,
+		Difficulty: int32(challenge.Difficulty),
+	}
+	if !challenge.ExpiresAt.IsZero() {
+		res.ExpiresAt = challenge.ExpiresAt.Unix()
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) GetJWKS(
+	ctx context.Context,
+	req *auth_grpc.GetJWKSRequest,
+) (*auth_grpc.GetJWKSResponse, error) {
+	jwks, err := s.auth.GetJWKS(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	keys := make([]*auth_grpc.JWK, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		keys = append(keys, &auth_grpc.JWK{
+			Kty: key.Kty,
+			Use: key.Use,
+			Kid: key.Kid,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return &auth_grpc.GetJWKSResponse{
+		Keys: keys,
+	}, nil
+}
+
+func (s *serverAPI) Introspect(
+	ctx context.Context,
+	req *auth_grpc.IntrospectRequest,
+) (*auth_grpc.IntrospectResponse, error) {
+	if err := validateIntrospect(req.GetToken(), req.GetAppId(), req.GetAppSecret()); err != nil {
+		return nil, err
+	}
+
+	res, err := s.auth.Introspect(ctx, int(req.GetAppId()), req.GetAppSecret(), req.GetToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if !res.Active {
+		return &auth_grpc.IntrospectResponse{}, nil
+	}
+
+	// tokens issued before iat was required have none
+	var iat int64
+	if !res.IssuedAt.IsZero() {
+		iat = res.IssuedAt.Unix()
+	}
+
+	return &auth_grpc.IntrospectResponse{
+		Active:   true,
+		Sub:      int32(res.Sub),
+		AppId:    int32(res.AppID),
+		Username: res.Username,
+		Exp:      res.ExpiresAt.Unix(),
+		Iat:      iat,
+		Scopes:   res.Scopes,
+		Roles:    res.Roles,
+	}, nil
+}
@@ -2,78 +2,180 @@ package auth
 
 import (
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/utils/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-func validateRegister(email, username, password string) error {
-	if err := validation.ValidationEmail(email); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+// violations collects field errors of a request, so client gets
+// all of them at once in errdetails.BadRequest.
+type violations []*errdetails.BadRequest_FieldViolation
 
-	if err := validation.ValidationUsername(username); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
+func (v *violations) check(field string, err error) {
+	if err == nil {
+		return
 	}
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	})
+}
 
-	if err := validation.ValidationPassword(password); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
 	}
+	return newStatus(codes.InvalidArgument, ReasonInvalidArgument, v[0].GetDescription(), v...)
+}
 
-	return nil
+func validateRegister(email, username, password string, appId int32) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	v.check("username", validation.ValidationUsername(username))
+	v.check("password", validation.ValidationPassword(password))
+	v.check("app_id", validation.ValidationAppID(appId))
+	return v.err()
 }
 
 func validateLogin(email, password string, appId int32) error {
-	if err := validation.ValidationEmail(email); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	v.check("password", validation.ValidationPassword(password))
+	v.check("app_id", validation.ValidationAppID(appId))
+	return v.err()
+}
 
-	if err := validation.ValidationPassword(password); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateChangePassword(newPassword string) error {
+	var v violations
+	v.check("newPassword", validation.ValidationPassword(newPassword))
+	return v.err()
+}
 
-	if err := validation.ValidationAppID(appId); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateChangeExpiredPassword(token string) error {
+	var v violations
+	v.check("password_change_token", validateNotEmptyToken(token))
+	return v.err()
+}
 
-	return nil
+func validateChangeUsername(username string) error {
+	var v violations
+	v.check("username", validation.ValidationUsername(username))
+	return v.err()
 }
 
-func validateChangePassword(token, newPassword string) error {
-	if err := validation.ValidationToken(token); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateResetPassword(email string, appId int32) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	v.check("app_id", validation.ValidationAppID(appId))
+	return v.err()
+}
 
-	if err := validation.ValidationPassword(newPassword); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateConfirmPasswordReset(token, newPassword string) error {
+	var v violations
+	v.check("token", validateNotEmptyToken(token))
+	v.check("new_password", validation.ValidationPassword(newPassword))
+	return v.err()
+}
 
-	return nil
+func validateVerifyEmail(token string) error {
+	var v violations
+	v.check("token", validateNotEmptyToken(token))
+	return v.err()
 }
 
-func validateChangeUsername(token, username string) error {
-	if err := validation.ValidationToken(token); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateResendVerification(email string) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	return v.err()
+}
 
-	if err := validation.ValidationUsername(username); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateChangeEmail(email string) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	return v.err()
+}
 
-	return nil
+func validateConfirmEmailChange(token string) error {
+	var v violations
+	v.check("token", validateNotEmptyToken(token))
+	return v.err()
 }
 
-func validateResetPassword(email string) error {
-	if err := validation.ValidationEmail(email); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateConfirmTOTPEnrollment(code string) error {
+	var v violations
+	v.check("code", validation.ValidationTOTPCode(code))
+	return v.err()
+}
 
-	return nil
+func validateVerifyMFA(mfaToken, code string) error {
+	var v violations
+	v.check("mfa_token", validateNotEmptyToken(mfaToken))
+	v.check("code", validation.ValidationMFACode(code))
+	return v.err()
 }
 
-func validateMe(token string) error {
-	if err := validation.ValidationToken(token); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
-	}
+func validateBeginPasskeyLogin(appId int32) error {
+	var v violations
+	v.check("app_id", validation.ValidationAppID(appId))
+	return v.err()
+}
+
+func validatePasskeyCredential(sessionID string, credential []byte) error {
+	var v violations
+	v.check("session_id", validateNotEmptyToken(sessionID))
+	v.check("credential", validation.ValidationPasskeyCredential(credential))
+	return v.err()
+}
+
+func validateRequestLoginCode(email string, appId int32) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	v.check("app_id", validation.ValidationAppID(appId))
+	return v.err()
+}
+
+func validateLoginWithCode(email string, appId int32, code string) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	v.check("app_id", validation.ValidationAppID(appId))
+	v.check("code", validation.ValidationLoginCode(code))
+	return v.err()
+}
+
+func validateUnlockAccount(email string) error {
+	var v violations
+	v.check("email", validation.ValidationEmail(email))
+	return v.err()
+}
+
+func validateRefresh(refreshToken string) error {
+	var v violations
+	v.check("refresh_token", validation.ValidationRefreshToken(refreshToken))
+	return v.err()
+}
 
+func validateRevokeToken(token string) error {
+	var v violations
+	v.check("token", validateNotEmptyToken(token))
+	return v.err()
+}
+
+func validateIntrospect(token string, appId int32, appSecret string) error {
+	var v violations
+	v.check("token", validateNotEmptyToken(token))
+	v.check("app_id", validation.ValidationAppID(appId))
+	v.check("app_secret", validation.ValidationAppSecret(appSecret))
+	return v.err()
+}
+
+func validateBodyToken(token string) error {
+	var v violations
+	v.check("token", validation.ValidationToken(token))
+	return v.err()
+}
+
+func validateNotEmptyToken(token string) error {
+	if token == "" {
+		return validation.ErrEmptyToken
+	}
 	return nil
-}
\ No newline at end of file
+}
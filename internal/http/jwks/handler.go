@@ -0,0 +1,52 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+const (
+	Path = "/.well-known/jwks.json"
+	// MaxAge is how long downstream services may cache keys,
+	// new keys are published at least that long in advance.
+	MaxAge = 300 * time.Second
+)
+
+var cacheControl = fmt.Sprintf("public, max-age=%d", int(MaxAge.Seconds()))
+
+type JWKSProvider interface {
+	GetJWKS(ctx context.Context) (jwks jwt.JWKS, err error)
+}
+
+func RegisterHandler(mux *http.ServeMux, log *slog.Logger, provider JWKSProvider) {
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		const op = "http.jwks.Handler"
+		log := log.With(slog.String("op", op))
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		jwks, err := provider.GetJWKS(r.Context())
+		if err != nil {
+			log.Error("failed to get jwks", slerr.Err(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", cacheControl)
+
+		if err := json.NewEncoder(w).Encode(jwks); err != nil {
+			log.Error("failed to write jwks", slerr.Err(err))
+		}
+	})
+}
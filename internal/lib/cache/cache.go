@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+const cleanupInterval = time.Minute
+
+type item[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a thread-safe in-memory key-value storage with per-item TTL.
+type Cache[K comparable, V any] struct {
+	mu          sync.RWMutex
+	items       map[K]item[V]
+	lastCleanup time.Time
+}
+
+func New[K comparable, V any]() *Cache[K, V] {
+	return &Cache[K, V]{
+		items:       make(map[K]item[V]),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var zero V
+
+	it, ok := c.items[key]
+	if !ok || time.Now().After(it.expiresAt) {
+		return zero, false
+	}
+
+	return it.value, true
+}
+
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.items[key] = item[V]{value: value, expiresAt: now.Add(ttl)}
+
+	if now.Sub(c.lastCleanup) > cleanupInterval {
+		c.cleanup(now)
+	}
+}
+
+// SetIfAbsent sets value unless key has one not expired yet.
+// It reports whether value is set, check and set are atomic.
+func (c *Cache[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if it, ok := c.items[key]; ok && !now.After(it.expiresAt) {
+		return false
+	}
+	c.items[key] = item[V]{value: value, expiresAt: now.Add(ttl)}
+
+	if now.Sub(c.lastCleanup) > cleanupInterval {
+		c.cleanup(now)
+	}
+
+	return true
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
+
+func (c *Cache[K, V]) cleanup(now time.Time) {
+	for k, it := range c.items {
+		if now.After(it.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+	c.lastCleanup = now
+}
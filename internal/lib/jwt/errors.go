@@ -3,5 +3,8 @@ package jwt
 import "errors"
 
 var (
-	ErrJWTDecode = errors.New("invalid token")
-)
\ No newline at end of file
+	ErrJWTDecode  = errors.New("invalid token")
+	ErrJWTStale   = errors.New("token issued before last password change")
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrInvalidKey = errors.New("invalid signing key")
+)
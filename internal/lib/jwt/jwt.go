@@ -1,25 +1,67 @@
 package jwt
 
 import (
+	"math"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
 )
 
 const (
 	ZeroValue = 0
 )
 
-func NewJWTToken(user models.User, app models.App, ttl time.Duration) (string, error) {
+type Claims struct {
+	Sub       int
+	AppID     int
+	JTI       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Scopes    []string
+	Roles     []string
+	// EmailVerified is false for tokens issued before the claim was added.
+	EmailVerified bool
+}
+
+// NewJWTToken signs token with active key of keyring
+// or with app secret (HS256) if keyring has no active key.
+func NewJWTToken(user models.User, app models.App, ttl time.Duration, keys *Keyring) (string, error) {
+	jti, err := opaque.NewID()
+	if err != nil {
+		return "", err
+	}
+
+	key, asymmetric := keys.Active()
+
 	token := jwt.New(jwt.SigningMethodHS256)
+	if asymmetric {
+		token = jwt.New(key.Method())
+		token.Header["kid"] = key.ID
+	}
+
+	now := time.Now()
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["sub"] = user.ID
 	claims["username"] = user.Username
-	claims["exp"] = time.Now().Add(ttl).Unix()
+	claims["iat"] = numericDate(now)
+	claims["exp"] = now.Add(ttl).Unix()
 	claims["app_id"] = app.ID
+	claims["jti"] = jti
+	claims["email_verified"] = user.IsEmailVerified()
+	if len(app.Scopes) > 0 {
+		claims["scope"] = strings.Join(app.Scopes, " ")
+	}
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
 
+	if asymmetric {
+		return token.SignedString(key.PrivateKey)
+	}
 	return token.SignedString([]byte(app.Secret))
 }
 
@@ -30,21 +72,98 @@ func GetAppIDFromJWTToken(token string) (int, error) {
 		claims, 
 		func(token *jwt.Token) (any, error) {return []byte{}, nil},
 	)
-	appID := int(claims["app_id"].(float64))
-	if appID == ZeroValue {
+	appID, ok := claims["app_id"].(float64)
+	if !ok || int(appID) == ZeroValue {
 		return 0, ErrJWTDecode
 	}
-	return appID, nil
+	return int(appID), nil
+}
+
+// GetSubFromJWTToken returns user id from token.
+// Token issued before notBefore (e.g. last password change) is rejected.
+func GetSubFromJWTToken(token string, app models.App, keys *Keyring, notBefore time.Time) (int, error) {
+	claims, err := ParseJWTToken(token, app, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := CheckIssuedAt(claims, notBefore); err != nil {
+		return 0, err
+	}
+
+	return claims.Sub, nil
+}
+
+// CheckIssuedAt rejects token issued before notBefore. Both are compared
+// with microseconds precision iat is written with. Tokens without iat
+// are issued before it was added and are not checked.
+func CheckIssuedAt(claims Claims, notBefore time.Time) error {
+	if claims.IssuedAt.IsZero() {
+		return nil
+	}
+
+	if claims.IssuedAt.Before(notBefore.Truncate(time.Microsecond)) {
+		return ErrJWTStale
+	}
+
+	return nil
+}
+
+// numericDate is NumericDate with microseconds fraction, so token issued
+// right after password change in the same second stays valid.
+func numericDate(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// parseNumericDate reads NumericDate with microseconds precision,
+// zero time is returned if claim is missing.
+func parseNumericDate(claims jwt.MapClaims, key string) (time.Time, error) {
+	v, ok := claims[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, ErrJWTDecode
+	}
+
+	return time.UnixMicro(int64(math.Round(seconds * 1e6))), nil
 }
 
-func GetSubFromJWTToken(token string, app models.App) (int, error) {
+// ParseJWTToken checks token signature and expiration and returns its claims.
+// Tokens with kid header are verified with keyring. Tokens signed with app
+// secret (HS256) are accepted only while keyring has no active key: once
+// asymmetric key is activated, storage always keeps one active, so app
+// secret can't be used to forge tokens anymore.
+func ParseJWTToken(token string, app models.App, keys *Keyring) (Claims, error) {
 	secret := []byte(app.Secret)
 
+	_, asymmetric := keys.Active()
+
+	var res Claims
+
 	claims := jwt.MapClaims{}
 	_, err := jwt.ParseWithClaims(
 		token,
 		claims, 
 		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && asymmetric {
+				return nil, ErrJWTDecode
+			}
+			if kid, ok := token.Header["kid"].(string); ok {
+				key, ok := keys.Get(kid)
+				if !ok {
+					return nil, ErrUnknownKey
+				}
+				if token.Method.Alg() != key.Algorithm {
+					return nil, ErrJWTDecode
+				}
+				return key.PublicKey(), nil
+			}
+			if asymmetric {
+				return nil, ErrUnknownKey
+			}
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, ErrJWTDecode
 			}
@@ -53,10 +172,42 @@ func GetSubFromJWTToken(token string, app models.App) (int, error) {
 	)
 
 	if err != nil {
-		return 0, err
+		return res, err
+	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return res, ErrJWTDecode
+	}
+	appID, ok := claims["app_id"].(float64)
+	if !ok {
+		return res, ErrJWTDecode
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return res, ErrJWTDecode
+	}
+	iat, err := parseNumericDate(claims, "iat")
+	if err != nil {
+		return res, err
+	}
+	jti, _ := claims["jti"].(string)
+	scope, _ := claims["scope"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	roles, _ := claims["roles"].([]any)
+	for _, role := range roles {
+		if r, ok := role.(string); ok {
+			res.Roles = append(res.Roles, r)
+		}
 	}
 
-	sub := int(claims["sub"].(float64))
+	res.Sub = int(sub)
+	res.AppID = int(appID)
+	res.JTI = jti
+	res.IssuedAt = iat
+	res.ExpiresAt = exp.Time
+	res.Scopes = strings.Fields(scope)
+	res.EmailVerified = emailVerified
 
-	return sub, nil
+	return res, nil
 }
@@ -0,0 +1,124 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+)
+
+func TestCheckIssuedAt(t *testing.T) {
+	change := time.Date(2024, 4, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		wantErr  bool
+	}{
+		{"earlier second", change.Add(-time.Second), true},
+		{"same second before change", change.Add(-100 * time.Millisecond), true},
+		{"same second after change", change.Add(100 * time.Millisecond), false},
+		{"at change", change, false},
+		{"no iat", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckIssuedAt(Claims{IssuedAt: tt.issuedAt}, change)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckIssuedAt error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseJWTTokenIssuedAt(t *testing.T) {
+	app := models.App{ID: 1, Secret: "secret"}
+	user := models.User{ID: 1, Username: "user"}
+
+	token, err := NewJWTToken(user, app, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseJWTToken(token, app, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.IssuedAt.Nanosecond()%int(time.Microsecond) != 0 {
+		t.Fatalf("IssuedAt = %s, want microseconds precision", claims.IssuedAt)
+	}
+
+	// tokens issued before iat was added stay valid
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    1,
+		"app_id": 1,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := legacy.SignedString([]byte(app.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err = ParseJWTToken(signed, app, nil)
+	if err != nil {
+		t.Fatalf("ParseJWTToken: %v", err)
+	}
+	if err := CheckIssuedAt(claims, time.Now()); err != nil {
+		t.Fatalf("CheckIssuedAt: %v", err)
+	}
+}
+
+func TestParseJWTTokenForgedHS256(t *testing.T) {
+	app := models.App{ID: 1, Secret: "secret"}
+	user := models.User{ID: 1, Username: "user"}
+
+	key, err := GenerateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// signed with app secret, as anybody knowing it could do
+	forged, err := NewJWTToken(user, app, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withKid := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    1,
+		"app_id": 1,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	withKid.Header["kid"] = key.ID
+	forgedWithKid, err := withKid.SignedString([]byte(app.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := NewJWTToken(user, app, time.Hour, NewKeyring(&key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		keys    *Keyring
+		wantErr bool
+	}{
+		{"HS256 without active key", forged, NewKeyring(nil), false},
+		{"HS256 without kid", forged, NewKeyring(&key), true},
+		{"HS256 with kid", forgedWithKid, NewKeyring(&key), true},
+		{"active key", signed, NewKeyring(&key), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJWTToken(tt.token, app, tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJWTToken error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
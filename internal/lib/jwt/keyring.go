@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	"sort"
+	"sync"
+)
+
+// Keyring holds the key used for signing new tokens
+// and all keys accepted for verification.
+// Empty keyring means tokens are signed with app secret (HS256).
+type Keyring struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]SigningKey
+}
+
+func NewKeyring(active *SigningKey, keys ...SigningKey) *Keyring {
+	k := &Keyring{}
+	k.Set(active, keys...)
+	return k
+}
+
+// Set replaces keys of the keyring. Active key is always accepted for verification.
+func (k *Keyring) Set(active *SigningKey, keys ...SigningKey) {
+	all := make(map[string]SigningKey, len(keys)+1)
+	for _, key := range keys {
+		all[key.ID] = key
+	}
+	if active != nil {
+		all[active.ID] = *active
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = active
+	k.keys = all
+}
+
+func (k *Keyring) Active() (SigningKey, bool) {
+	if k == nil {
+		return SigningKey{}, false
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active == nil {
+		return SigningKey{}, false
+	}
+	return *k.active, true
+}
+
+func (k *Keyring) Get(kid string) (SigningKey, bool) {
+	if k == nil {
+		return SigningKey{}, false
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *Keyring) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if k == nil {
+		return jwks
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
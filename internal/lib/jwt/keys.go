@@ -0,0 +1,207 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is an asymmetric key used to sign tokens.
+// Its public part is published in JWKS under ID.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParsePrivateKey parses PEM encoded RSA, ECDSA P-256 or Ed25519 private key.
+// Algorithm is chosen by key type, key id is RFC 7638 thumbprint.
+func ParsePrivateKey(data []byte) (SigningKey, error) {
+	var key SigningKey
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key, ErrInvalidKey
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return key, ErrInvalidKey
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return key, ErrInvalidKey
+	}
+
+	return NewSigningKey(signer)
+}
+
+// MarshalPrivateKey encodes private key to PKCS #8 PEM.
+func MarshalPrivateKey(key SigningKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func NewSigningKey(signer crypto.Signer) (SigningKey, error) {
+	key := SigningKey{PrivateKey: signer}
+
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		key.Algorithm = AlgRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return key, ErrInvalidKey
+		}
+		key.Algorithm = AlgES256
+	case ed25519.PublicKey:
+		key.Algorithm = AlgEdDSA
+	default:
+		return key, ErrInvalidKey
+	}
+
+	key.ID = thumbprint(key.JWK())
+
+	return key, nil
+}
+
+func (k SigningKey) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+func (k SigningKey) JWK() JWK {
+	jwk := JWK{
+		Use: "sig",
+		Kid: k.ID,
+		Alg: k.Algorithm,
+	}
+
+	switch pub := k.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = b64(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	}
+
+	return jwk
+}
+
+// thumbprint implements RFC 7638: hash of required members in lexicographic order.
+func thumbprint(jwk JWK) string {
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+
+	return b64(sum[:])
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// GenerateSigningKey creates new key for the algorithm.
+func GenerateSigningKey(alg string) (SigningKey, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch alg {
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return NewSigningKey(signer)
+}
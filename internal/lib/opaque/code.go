@@ -0,0 +1,19 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// NewCode returns random numeric code of digits length, e.g. for email.
+func NewCode(digits int) (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
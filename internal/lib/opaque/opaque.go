@@ -0,0 +1,36 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const (
+	TokenSize = 32
+	IDSize    = 16
+)
+
+// NewToken returns random url-safe string. Only its hash should be stored.
+func NewToken() (string, error) {
+	buf := make([]byte, TokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewID returns random hex identifier.
+func NewID() (string, error) {
+	buf := make([]byte, IDSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func Hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,37 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// recoveryAlphabet has no easily confused characters (0/o, 1/l/i).
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+const RecoveryCodeLength = 10
+
+// NewRecoveryCode returns random code formatted as "xxxxx-xxxxx".
+func NewRecoveryCode() (string, error) {
+	buf := make([]byte, RecoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, c := range buf {
+		if i == RecoveryCodeLength/2 {
+			b.WriteByte('-')
+		}
+		// modulo bias is negligible for 31 symbols and 256 values
+		b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+	}
+
+	return b.String(), nil
+}
+
+// NormalizeRecoveryCode drops separators and case, so code is hashed
+// the same way however user typed it.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
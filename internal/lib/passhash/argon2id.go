@@ -0,0 +1,85 @@
+package passhash
+
+import (
+	"crypto/subtle"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idID = "argon2id"
+
+// Argon2id parameters, Memory is in KiB.
+type Argon2id struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen int
+	KeyLen  uint32
+}
+
+func (a Argon2id) ID() string {
+	return argon2idID
+}
+
+func (a Argon2id) Hash(password []byte) (string, error) {
+	salt, err := salt(a.SaltLen)
+	if err != nil {
+		return "", err
+	}
+
+	return phc{
+		id:      argon2idID,
+		version: argon2.Version,
+		params: map[string]int{
+			"m": int(a.Memory),
+			"t": int(a.Time),
+			"p": int(a.Threads),
+		},
+		salt: salt,
+		hash: argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLen),
+	}.String(), nil
+}
+
+func (a Argon2id) Verify(password []byte, hash string) (bool, error) {
+	p, err := a.parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey(
+		password,
+		p.salt,
+		uint32(p.params["t"]),
+		uint32(p.params["m"]),
+		uint8(p.params["p"]),
+		uint32(len(p.hash)),
+	)
+
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+func (a Argon2id) NeedsRehash(hash string) bool {
+	p, err := a.parse(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.params["m"] != int(a.Memory) ||
+		p.params["t"] != int(a.Time) ||
+		p.params["p"] != int(a.Threads) ||
+		len(p.salt) != a.SaltLen ||
+		len(p.hash) != int(a.KeyLen)
+}
+
+func (a Argon2id) parse(hash string) (phc, error) {
+	p, err := parsePHC(hash)
+	if err != nil {
+		return p, err
+	}
+	if p.id != argon2idID || p.version != argon2.Version ||
+		p.params["m"] <= 0 || p.params["t"] <= 0 || p.params["p"] <= 0 || p.params["p"] > 255 {
+		return p, ErrInvalidHash
+	}
+
+	return p, nil
+}
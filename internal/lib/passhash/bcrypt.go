@@ -0,0 +1,41 @@
+package passhash
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const bcryptID = "bcrypt"
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) ID() string {
+	return bcryptID
+}
+
+func (b Bcrypt) Hash(password []byte) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(password, b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(password []byte, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), password)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
@@ -0,0 +1,95 @@
+// Package passhash hashes passwords with bcrypt, scrypt or argon2id.
+// Hashes are self-describing: argon2id and scrypt use PHC string format,
+// bcrypt keeps its own "$2a$cost$..." format, so old hashes stay valid.
+package passhash
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidHash      = errors.New("invalid password hash")
+)
+
+// Hasher is one hashing algorithm with its current parameters.
+type Hasher interface {
+	// ID is algorithm identifier in hash, e.g. "argon2id".
+	ID() string
+	Hash(password []byte) (string, error)
+	// Verify compares password with hash of this algorithm.
+	Verify(password []byte, hash string) (bool, error)
+	// NeedsRehash reports whether hash is made with other parameters.
+	NeedsRehash(hash string) bool
+}
+
+// Set hashes new passwords with current hasher and verifies hashes
+// of any known one.
+type Set struct {
+	current Hasher
+	hashers map[string]Hasher
+}
+
+// NewSet returns set hashing with current, others are used to verify only.
+func NewSet(current Hasher, others ...Hasher) *Set {
+	s := &Set{
+		current: current,
+		hashers: map[string]Hasher{current.ID(): current},
+	}
+	for _, h := range others {
+		if _, ok := s.hashers[h.ID()]; !ok {
+			s.hashers[h.ID()] = h
+		}
+	}
+
+	return s
+}
+
+// Hash hashes password with current hasher.
+func (s *Set) Hash(password string) ([]byte, error) {
+	hash, err := s.current.Hash([]byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(hash), nil
+}
+
+// Verify compares password with hash. Rehash is true if password matches,
+// but hash is made by other algorithm or with other parameters.
+func (s *Set) Verify(password string, hash []byte) (ok, rehash bool, err error) {
+	h, ok := s.hashers[algorithm(string(hash))]
+	if !ok {
+		return false, false, ErrUnknownAlgorithm
+	}
+
+	ok, err = h.Verify([]byte(password), string(hash))
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	return true, h != s.current || h.NeedsRehash(string(hash)), nil
+}
+
+// algorithm returns identifier of hash, all bcrypt versions are "bcrypt".
+func algorithm(hash string) string {
+	parts := strings.SplitN(hash, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return ""
+	}
+
+	if strings.HasPrefix(parts[1], "2") {
+		return bcryptID
+	}
+	return parts[1]
+}
+
+func salt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
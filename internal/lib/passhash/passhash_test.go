@@ -0,0 +1,121 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	testBcrypt   = Bcrypt{Cost: bcrypt.MinCost}
+	testScrypt   = Scrypt{LogN: 4, R: 8, P: 1, SaltLen: 16, KeyLen: 32}
+	testArgon2id = Argon2id{Memory: 64, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+)
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		hasher Hasher
+		prefix string
+	}{
+		{hasher: testBcrypt, prefix: "$2a$04$"},
+		{hasher: testScrypt, prefix: "$scrypt$ln=4,r=8,p=1$"},
+		{hasher: testArgon2id, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hasher.ID(), func(t *testing.T) {
+			hash, err := tt.hasher.Hash([]byte("password"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Fatalf("hash = %q, want prefix %q", hash, tt.prefix)
+			}
+			if got := algorithm(hash); got != tt.hasher.ID() {
+				t.Fatalf("algorithm = %q, want %q", got, tt.hasher.ID())
+			}
+
+			if ok, err := tt.hasher.Verify([]byte("password"), hash); err != nil || !ok {
+				t.Fatalf("verify = %v, %v, want true", ok, err)
+			}
+			if ok, err := tt.hasher.Verify([]byte("wrong"), hash); err != nil || ok {
+				t.Fatalf("verify wrong = %v, %v, want false", ok, err)
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Fatal("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+func TestSetRehash(t *testing.T) {
+	stronger := testArgon2id
+	stronger.Time = 2
+
+	tests := []struct {
+		name       string
+		hashedBy   Hasher
+		set        *Set
+		password   string
+		wantOK     bool
+		wantRehash bool
+	}{
+		{
+			name:     "current",
+			hashedBy: testArgon2id,
+			set:      NewSet(testArgon2id, testBcrypt),
+			password: "password",
+			wantOK:   true,
+		},
+		{
+			name:       "other algorithm",
+			hashedBy:   testBcrypt,
+			set:        NewSet(testArgon2id, testBcrypt),
+			password:   "password",
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "outdated parameters",
+			hashedBy:   testArgon2id,
+			set:        NewSet(stronger),
+			password:   "password",
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "wrong password",
+			hashedBy: testBcrypt,
+			set:      NewSet(testArgon2id, testBcrypt),
+			password: "wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hashedBy.Hash([]byte("password"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, rehash, err := tt.set.Verify(tt.password, []byte(hash))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Fatalf("verify = %v, %v, want %v, %v", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestSetUnknownAlgorithm(t *testing.T) {
+	set := NewSet(testArgon2id)
+
+	for _, hash := range []string{"", "plain", "$md5$abc", "$2a$04$" + strings.Repeat("a", 53)} {
+		if _, _, err := set.Verify("password", []byte(hash)); err != ErrUnknownAlgorithm {
+			t.Fatalf("verify %q: err = %v, want %v", hash, err, ErrUnknownAlgorithm)
+		}
+	}
+}
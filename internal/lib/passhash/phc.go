@@ -0,0 +1,92 @@
+package passhash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var b64 = base64.RawStdEncoding
+
+// phc is hash in PHC string format:
+// $<id>[$v=<version>]$<param>=<value>(,<param>=<value>)*$<salt>$<hash>
+type phc struct {
+	id      string
+	version int
+	params  map[string]int
+	salt    []byte
+	hash    []byte
+}
+
+func (p phc) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("$" + p.id)
+	if p.version != 0 {
+		sb.WriteString("$v=" + strconv.Itoa(p.version))
+	}
+
+	sb.WriteString("$")
+	for i, name := range paramOrder[p.id] {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("%s=%d", name, p.params[name]))
+	}
+
+	sb.WriteString("$" + b64.EncodeToString(p.salt))
+	sb.WriteString("$" + b64.EncodeToString(p.hash))
+
+	return sb.String()
+}
+
+// paramOrder is order of parameters of algorithm in hash.
+var paramOrder = map[string][]string{
+	argon2idID: {"m", "t", "p"},
+	scryptID:   {"ln", "r", "p"},
+}
+
+func parsePHC(hash string) (phc, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return phc{}, ErrInvalidHash
+	}
+
+	p := phc{id: parts[1], params: make(map[string]int)}
+	parts = parts[2:]
+
+	if strings.HasPrefix(parts[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(parts[0], "v="))
+		if err != nil {
+			return phc{}, ErrInvalidHash
+		}
+		p.version = version
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return phc{}, ErrInvalidHash
+	}
+
+	for _, param := range strings.Split(parts[0], ",") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return phc{}, ErrInvalidHash
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return phc{}, ErrInvalidHash
+		}
+		p.params[name] = n
+	}
+
+	var err error
+	if p.salt, err = b64.DecodeString(parts[1]); err != nil {
+		return phc{}, ErrInvalidHash
+	}
+	if p.hash, err = b64.DecodeString(parts[2]); err != nil || len(p.hash) == 0 {
+		return phc{}, ErrInvalidHash
+	}
+
+	return p, nil
+}
@@ -0,0 +1,85 @@
+package passhash
+
+import (
+	"crypto/subtle"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const scryptID = "scrypt"
+
+// Scrypt parameters, cost N is 2^LogN.
+type Scrypt struct {
+	LogN    int
+	R       int
+	P       int
+	SaltLen int
+	KeyLen  int
+}
+
+func (s Scrypt) ID() string {
+	return scryptID
+}
+
+func (s Scrypt) Hash(password []byte) (string, error) {
+	salt, err := salt(s.SaltLen)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key(password, salt, 1<<s.LogN, s.R, s.P, s.KeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return phc{
+		id: scryptID,
+		params: map[string]int{
+			"ln": s.LogN,
+			"r":  s.R,
+			"p":  s.P,
+		},
+		salt: salt,
+		hash: key,
+	}.String(), nil
+}
+
+func (s Scrypt) Verify(password []byte, hash string) (bool, error) {
+	p, err := s.parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scrypt.Key(password, p.salt, 1<<p.params["ln"], p.params["r"], p.params["p"], len(p.hash))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+func (s Scrypt) NeedsRehash(hash string) bool {
+	p, err := s.parse(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.params["ln"] != s.LogN ||
+		p.params["r"] != s.R ||
+		p.params["p"] != s.P ||
+		len(p.salt) != s.SaltLen ||
+		len(p.hash) != s.KeyLen
+}
+
+func (s Scrypt) parse(hash string) (phc, error) {
+	p, err := parsePHC(hash)
+	if err != nil {
+		return p, err
+	}
+	if p.id != scryptID || p.params["ln"] <= 0 || p.params["ln"] > 30 ||
+		p.params["r"] <= 0 || p.params["p"] <= 0 {
+		return p, ErrInvalidHash
+	}
+
+	return p, nil
+}
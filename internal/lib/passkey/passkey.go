@@ -0,0 +1,248 @@
+// Package passkey runs WebAuthn registration and login ceremonies.
+// Options and session data are returned as JSON, so caller can store
+// session anywhere and pass options to browser as is.
+package passkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+)
+
+var (
+	ErrInvalidSession    = errors.New("invalid passkey session")
+	ErrInvalidCredential = errors.New("invalid passkey credential")
+)
+
+type Config struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+	Timeout       time.Duration
+}
+
+type WebAuthn struct {
+	wa      *webauthn.WebAuthn
+	timeout time.Duration
+}
+
+func New(cfg Config) (*WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cfg.Timeout,
+		TimeoutUVD: cfg.Timeout,
+	}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthn{wa: wa, timeout: cfg.Timeout}, nil
+}
+
+// Timeout is how long session of started ceremony is valid.
+func (w *WebAuthn) Timeout() time.Duration {
+	return w.timeout
+}
+
+// CredentialLookup returns owner of credential found by discoverable login
+// and all passkeys of the owner.
+type CredentialLookup func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error)
+
+// BeginRegistration returns credential creation options for browser
+// and session data. Passkeys registered already are excluded.
+func (w *WebAuthn) BeginRegistration(user models.User, creds []models.WebAuthnCredential) ([]byte, []byte, error) {
+	u := newUser(user, creds)
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(creds))
+	for _, cred := range u.creds {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, session, err := w.wa.BeginRegistration(
+		u,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return marshal(creation, session)
+}
+
+// FinishRegistration verifies authenticator response and returns new passkey.
+func (w *WebAuthn) FinishRegistration(
+	user models.User,
+	creds []models.WebAuthnCredential,
+	sessionData, response []byte,
+) (models.WebAuthnCredential, error) {
+	var session webauthn.SessionData
+	if err := json.Unmarshal(sessionData, &session); err != nil {
+		return models.WebAuthnCredential{}, ErrInvalidSession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return models.WebAuthnCredential{}, errors.Join(ErrInvalidCredential, err)
+	}
+
+	cred, err := w.wa.CreateCredential(newUser(user, creds), session, parsed)
+	if err != nil {
+		return models.WebAuthnCredential{}, errors.Join(ErrInvalidCredential, err)
+	}
+
+	return fromCredential(user.ID, *cred), nil
+}
+
+// BeginLogin returns assertion options for discoverable login, so user
+// is not asked for email, and session data.
+func (w *WebAuthn) BeginLogin() ([]byte, []byte, error) {
+	assertion, session, err := w.wa.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return marshal(assertion, session)
+}
+
+// FinishLogin verifies assertion and returns its user and passkey
+// with updated sign counter.
+func (w *WebAuthn) FinishLogin(
+	sessionData, response []byte,
+	lookup CredentialLookup,
+) (models.User, models.WebAuthnCredential, error) {
+	var session webauthn.SessionData
+	if err := json.Unmarshal(sessionData, &session); err != nil {
+		return models.User{}, models.WebAuthnCredential{}, ErrInvalidSession
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return models.User{}, models.WebAuthnCredential{}, errors.Join(ErrInvalidCredential, err)
+	}
+
+	var found *user
+
+	cred, err := w.wa.ValidateDiscoverableLogin(
+		func(rawID, userHandle []byte) (webauthn.User, error) {
+			owner, creds, err := lookup(rawID, userHandle)
+			if err != nil {
+				return nil, err
+			}
+			found = newUser(owner, creds)
+			return found, nil
+		},
+		session,
+		parsed,
+	)
+	if err != nil {
+		return models.User{}, models.WebAuthnCredential{}, errors.Join(ErrInvalidCredential, err)
+	}
+
+	if cred.Authenticator.CloneWarning {
+		return models.User{}, models.WebAuthnCredential{}, errors.Join(
+			ErrInvalidCredential,
+			errors.New("sign counter went back, authenticator may be cloned"),
+		)
+	}
+
+	return found.User, fromCredential(found.ID, *cred), nil
+}
+
+// UserHandle is WebAuthn user id of user.
+func UserHandle(user models.User) []byte {
+	return []byte(strconv.Itoa(int(user.ID)))
+}
+
+// user adapts models.User to webauthn.User.
+type user struct {
+	models.User
+	creds []webauthn.Credential
+}
+
+func newUser(u models.User, creds []models.WebAuthnCredential) *user {
+	res := &user{User: u, creds: make([]webauthn.Credential, 0, len(creds))}
+	for _, cred := range creds {
+		res.creds = append(res.creds, toCredential(cred))
+	}
+	return res
+}
+
+func (u *user) WebAuthnID() []byte                         { return UserHandle(u.User) }
+func (u *user) WebAuthnName() string                       { return u.Email }
+func (u *user) WebAuthnDisplayName() string                { return u.Username }
+func (u *user) WebAuthnCredentials() []webauthn.Credential { return u.creds }
+func (u *user) WebAuthnIcon() string                       { return "" }
+
+func toCredential(cred models.WebAuthnCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(cred.Transports))
+	for _, t := range cred.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              cred.CredentialID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: cred.BackupEligible,
+			BackupState:    cred.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    cred.AAGUID,
+			SignCount: cred.SignCount,
+		},
+	}
+}
+
+func fromCredential(userID int32, cred webauthn.Credential) models.WebAuthnCredential {
+	transports := make([]string, 0, len(cred.Transport))
+	for _, t := range cred.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return models.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+	}
+}
+
+func marshal(options any, session *webauthn.SessionData) ([]byte, []byte, error) {
+	optionsData, err := json.Marshal(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return optionsData, sessionData, nil
+}
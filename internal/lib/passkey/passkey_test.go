@@ -0,0 +1,298 @@
+package passkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// authenticator is software authenticator with one ES256 passkey.
+type authenticator struct {
+	t          *testing.T
+	key        *ecdsa.PrivateKey
+	credID     []byte
+	userHandle []byte
+	signCount  uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credID := make([]byte, 16)
+	if _, err := rand.Read(credID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &authenticator{t: t, key: key, credID: credID}
+}
+
+// create answers navigator.credentials.create() options.
+func (a *authenticator) create(options []byte) []byte {
+	a.t.Helper()
+
+	var opts struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	}
+	a.unmarshal(options, &opts)
+
+	a.userHandle = a.decode(opts.PublicKey.User.ID)
+
+	coseKey, err := webauthncbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	authData := a.authData(0x45) // UP, UV, AT
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credID)))
+	authData = append(authData, a.credID...)
+	authData = append(authData, coseKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return a.marshal(map[string]any{
+		"id":    a.encode(a.credID),
+		"rawId": a.encode(a.credID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    a.encode(a.clientData("webauthn.create", opts.PublicKey.Challenge)),
+			"attestationObject": a.encode(attestation),
+		},
+	})
+}
+
+// get answers navigator.credentials.get() options.
+func (a *authenticator) get(options []byte) []byte {
+	a.t.Helper()
+
+	var opts struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	a.unmarshal(options, &opts)
+
+	a.signCount++
+	authData := a.authData(0x05) // UP, UV
+	clientData := a.clientData("webauthn.get", opts.PublicKey.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return a.marshal(map[string]any{
+		"id":    a.encode(a.credID),
+		"rawId": a.encode(a.credID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    a.encode(clientData),
+			"authenticatorData": a.encode(authData),
+			"signature":         a.encode(signature),
+			"userHandle":        a.encode(a.userHandle),
+		},
+	})
+}
+
+func (a *authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func (a *authenticator) clientData(typ, challenge string) []byte {
+	return a.marshal(map[string]any{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+}
+
+func (a *authenticator) encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *authenticator) decode(data string) []byte {
+	a.t.Helper()
+
+	res, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return res
+}
+
+func (a *authenticator) marshal(v any) []byte {
+	a.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+func (a *authenticator) unmarshal(data []byte, v any) {
+	a.t.Helper()
+
+	if err := json.Unmarshal(data, v); err != nil {
+		a.t.Fatal(err)
+	}
+}
+
+func newTestWebAuthn(t *testing.T) *WebAuthn {
+	t.Helper()
+
+	w, err := New(Config{
+		RPID:          testRPID,
+		RPDisplayName: "test",
+		RPOrigins:     []string{testOrigin},
+		Timeout:       time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func register(t *testing.T, w *WebAuthn, auth *authenticator, user models.User) models.WebAuthnCredential {
+	t.Helper()
+
+	options, session, err := w.BeginRegistration(user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cred, err := w.FinishRegistration(user, nil, session, auth.create(options))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	w := newTestWebAuthn(t)
+	auth := newAuthenticator(t)
+	user := models.User{ID: 42, Email: "user@example.com", Username: "user"}
+
+	cred := register(t, w, auth, user)
+	if cred.UserID != user.ID {
+		t.Fatalf("credential user = %d, want %d", cred.UserID, user.ID)
+	}
+
+	options, session, err := w.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, updated, err := w.FinishLogin(session, auth.get(options), func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+		return user, []models.WebAuthnCredential{cred}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("user = %d, want %d", got.ID, user.ID)
+	}
+	if updated.SignCount != 1 {
+		t.Fatalf("sign count = %d, want 1", updated.SignCount)
+	}
+}
+
+func TestFinishLoginErrors(t *testing.T) {
+	w := newTestWebAuthn(t)
+	user := models.User{ID: 42, Email: "user@example.com", Username: "user"}
+
+	auth := newAuthenticator(t)
+	cred := register(t, w, auth, user)
+
+	lookup := func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+		return user, []models.WebAuthnCredential{cred}, nil
+	}
+
+	tests := []struct {
+		name   string
+		login  func(options []byte) []byte
+		lookup CredentialLookup
+	}{
+		{
+			name:   "unknown authenticator",
+			login:  newAuthenticator(t).get,
+			lookup: lookup,
+		},
+		{
+			name:  "unknown credential",
+			login: auth.get,
+			lookup: func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+				return models.User{}, nil, errors.New("not found")
+			},
+		},
+		{
+			name: "replayed counter",
+			login: func(options []byte) []byte {
+				auth.signCount = 0
+				return auth.get(options)
+			},
+			lookup: func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+				used := cred
+				used.SignCount = 10
+				return user, []models.WebAuthnCredential{used}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, session, err := w.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = w.FinishLogin(session, tt.login(options), tt.lookup)
+			if !errors.Is(err, ErrInvalidCredential) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCredential)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Package passpolicy checks new passwords against configurable rules.
+package passpolicy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy is set of password rules. It is decoded from JSON when app
+// overrides default policy, fields missing in JSON keep default values.
+type Policy struct {
+	MinLength int `json:"min_length"`
+	// MaxLength of zero is unlimited.
+	MaxLength     int  `json:"max_length"`
+	RequireLower  bool `json:"require_lower"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	// BanUserInputs rejects passwords containing email or username.
+	BanUserInputs bool `json:"ban_user_inputs"`
+	// BannedWords are rejected anywhere in password, case insensitive.
+	BannedWords []string `json:"banned_words"`
+	// MinEntropy is minimal Strength in bits.
+	MinEntropy float64 `json:"min_entropy"`
+}
+
+// Violation is one broken rule.
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+const (
+	RuleMinLength  = "min_length"
+	RuleMaxLength  = "max_length"
+	RuleLower      = "require_lower"
+	RuleUpper      = "require_upper"
+	RuleDigit      = "require_digit"
+	RuleSymbol     = "require_symbol"
+	RuleUserInputs = "ban_user_inputs"
+	RuleBannedWord = "banned_words"
+	RuleMinEntropy = "min_entropy"
+	// RuleBreached is not checked by Policy, it is reported
+	// by caller when password is found in breach corpus.
+	RuleBreached = "breached"
+	// RuleHistory is not checked by Policy either, it is reported
+	// when password matches one of previous passwords of user.
+	RuleHistory = "password_history"
+)
+
+// minUserInputLen is length of user input parts checked by BanUserInputs,
+// shorter ones match too many passwords by chance.
+const minUserInputLen = 3
+
+// Check returns broken rules, it is empty if password is accepted.
+// User inputs are email, username and alike.
+func (p Policy) Check(password string, userInputs ...string) []Violation {
+	var violations []Violation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Description: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(RuleMinLength, "password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(RuleMaxLength, "password must be at most %d characters", p.MaxLength)
+	}
+
+	classes := charClasses(password)
+	if p.RequireLower && !classes.lower {
+		add(RuleLower, "password must contain lowercase letter")
+	}
+	if p.RequireUpper && !classes.upper {
+		add(RuleUpper, "password must contain uppercase letter")
+	}
+	if p.RequireDigit && !classes.digit {
+		add(RuleDigit, "password must contain digit")
+	}
+	if p.RequireSymbol && !classes.symbol {
+		add(RuleSymbol, "password must contain symbol")
+	}
+
+	lower := strings.ToLower(password)
+	if p.BanUserInputs {
+		for _, input := range userInputParts(userInputs) {
+			if strings.Contains(lower, input) {
+				add(RuleUserInputs, "password must not contain email or username")
+				break
+			}
+		}
+	}
+	for _, word := range p.BannedWords {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			add(RuleBannedWord, "password must not contain common words")
+			break
+		}
+	}
+
+	if p.MinEntropy > 0 && Strength(password) < p.MinEntropy {
+		add(RuleMinEntropy, "password is too weak")
+	}
+
+	return violations
+}
+
+// Strength estimates entropy of password in bits: length times log2
+// of alphabet of used character classes. Repeated characters in a row
+// are not counted, "aaaaaa" is as weak as "a".
+func Strength(password string) float64 {
+	classes := charClasses(password)
+
+	pool := 0
+	if classes.lower {
+		pool += 26
+	}
+	if classes.upper {
+		pool += 26
+	}
+	if classes.digit {
+		pool += 10
+	}
+	if classes.symbol {
+		pool += 33
+	}
+	if classes.other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	length := 0
+	var prev rune
+	for i, r := range password {
+		if i == 0 || r != prev {
+			length++
+		}
+		prev = r
+	}
+
+	return float64(length) * math.Log2(float64(pool))
+}
+
+type classes struct {
+	lower, upper, digit, symbol, other bool
+}
+
+func charClasses(password string) classes {
+	var c classes
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			c.lower = true
+		case r >= 'A' && r <= 'Z':
+			c.upper = true
+		case r >= '0' && r <= '9':
+			c.digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			c.symbol = true
+		case unicode.IsLower(r):
+			c.lower, c.other = true, true
+		case unicode.IsUpper(r):
+			c.upper, c.other = true, true
+		default:
+			c.other = true
+		}
+	}
+	return c
+}
+
+// userInputParts splits inputs into lowercase words. Only local part of email
+// is used: "john.doe@mail.com" gives "john.doe", "john" and "doe".
+func userInputParts(inputs []string) []string {
+	var parts []string
+	for _, input := range inputs {
+		input = strings.ToLower(input)
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			input = local
+		}
+
+		parts = append(parts, input)
+		parts = append(parts, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	res := parts[:0]
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minUserInputLen {
+			res = append(res, part)
+		}
+	}
+	return res
+}
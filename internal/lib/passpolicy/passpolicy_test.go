@@ -0,0 +1,90 @@
+package passpolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := Policy{
+		MinLength:     8,
+		MaxLength:     64,
+		RequireDigit:  true,
+		BanUserInputs: true,
+		BannedWords:   []string{"qwerty"},
+		MinEntropy:    40,
+	}
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		password  string
+		wantRules []string
+	}{
+		{
+			name:     "accepted",
+			policy:   policy,
+			password: "correct horse battery 9",
+		},
+		{
+			name:      "short and weak",
+			policy:    policy,
+			password:  "abc1",
+			wantRules: []string{RuleMinLength, RuleMinEntropy},
+		},
+		{
+			name:      "too long",
+			policy:    Policy{MaxLength: 4},
+			password:  "abcde",
+			wantRules: []string{RuleMaxLength},
+		},
+		{
+			name:      "no digit",
+			policy:    policy,
+			password:  "correct horse battery",
+			wantRules: []string{RuleDigit},
+		},
+		{
+			name:      "contains email",
+			policy:    policy,
+			password:  "my name is John 1984!",
+			wantRules: []string{RuleUserInputs},
+		},
+		{
+			name:     "email domain is not banned",
+			policy:   Policy{BanUserInputs: true},
+			password: "welcome to example",
+		},
+		{
+			name:      "banned word",
+			policy:    policy,
+			password:  "QWERTY-stronger-2024",
+			wantRules: []string{RuleBannedWord},
+		},
+		{
+			name:      "repeated characters",
+			policy:    Policy{MinEntropy: 20},
+			password:  "aaaaaaaaaaaaaaaa",
+			wantRules: []string{RuleMinEntropy},
+		},
+		{
+			name:      "all classes required",
+			policy:    Policy{RequireLower: true, RequireUpper: true, RequireSymbol: true},
+			password:  "12345",
+			wantRules: []string{RuleLower, RuleUpper, RuleSymbol},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range tt.policy.Check(tt.password, "john.doe@example.com", "jd") {
+				rules = append(rules, v.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Fatalf("rules = %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package pow
+
+import (
+	"context"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/cache"
+)
+
+// Memory remembers challenges used on one instance.
+type Memory struct {
+	used *cache.Cache[string, bool]
+}
+
+func NewMemory() *Memory {
+	return &Memory{used: cache.New[string, bool]()}
+}
+
+// UseChallenge implements Backend.
+func (m *Memory) UseChallenge(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	return m.used.SetIfAbsent(id, true, time.Until(expiresAt)), nil
+}
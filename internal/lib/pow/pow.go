@@ -0,0 +1,184 @@
+// Package pow implements hashcash-style proof of work. Server issues signed
+// challenge, client finds nonce such that SHA-256 of "challenge:nonce"
+// starts with Difficulty zero bits.
+package pow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey       = errors.New("pow key must be base64 encoded 32 bytes")
+	ErrInvalidChallenge = errors.New("invalid challenge")
+	ErrChallengeExpired = errors.New("challenge expired")
+	ErrChallengeUsed    = errors.New("challenge already used")
+	ErrInvalidSolution  = errors.New("invalid solution")
+)
+
+const keySize = 32
+
+// Challenge is issued to client before protected request.
+type Challenge struct {
+	Value      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+type Config struct {
+	// Key is base64 encoded HMAC key, instances verifying
+	// challenges of each other must share it.
+	Key string
+	TTL time.Duration
+	// Difficulty is used while rate is under Threshold challenges per Window,
+	// it grows by one bit each time rate doubles, up to MaxDifficulty.
+	Difficulty    int
+	MaxDifficulty int
+	Threshold     int
+	Window        time.Duration
+}
+
+// Backend remembers used challenges until they expire.
+type Backend interface {
+	// UseChallenge marks challenge used, ok is false if it is used already.
+	UseChallenge(ctx context.Context, id string, expiresAt time.Time) (ok bool, err error)
+}
+
+// Issuer issues and verifies challenges. Solved challenge is accepted once,
+// used ones are remembered by backend, instances sharing it reject
+// challenges used on each other.
+type Issuer struct {
+	key  []byte
+	cfg  Config
+	rate *rateCounter
+	used Backend
+}
+
+func New(cfg Config, used Backend) (*Issuer, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil || len(key) != keySize {
+		return nil, ErrInvalidKey
+	}
+	cfg.MaxDifficulty = max(cfg.MaxDifficulty, cfg.Difficulty)
+
+	return &Issuer{
+		key:  key,
+		cfg:  cfg,
+		rate: newRateCounter(cfg.Window),
+		used: used,
+	}, nil
+}
+
+// Issue returns new challenge, difficulty depends on recent rate of them.
+func (i *Issuer) Issue(now time.Time) (Challenge, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return Challenge{}, err
+	}
+
+	difficulty := i.difficulty(i.rate.add(now))
+	expiresAt := now.Add(i.cfg.TTL)
+
+	payload := strings.Join([]string{
+		strconv.FormatInt(expiresAt.Unix(), 10),
+		strconv.Itoa(difficulty),
+		base64.RawURLEncoding.EncodeToString(random),
+	}, ".")
+
+	return Challenge{
+		Value:      payload + "." + i.sign(payload),
+		Difficulty: difficulty,
+		ExpiresAt:  time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// Verify checks signature, expiry and solution of challenge and marks it used.
+func (i *Issuer) Verify(ctx context.Context, value, nonce string, now time.Time) error {
+	parts := strings.Split(value, ".")
+	if len(parts) != 4 {
+		return ErrInvalidChallenge
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(i.sign(payload))) {
+		return ErrInvalidChallenge
+	}
+
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	expiresAt := time.Unix(expires, 0)
+	if !now.Before(expiresAt) {
+		return ErrChallengeExpired
+	}
+
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+
+	if !Solved(value, nonce, difficulty) {
+		return ErrInvalidSolution
+	}
+
+	ok, err := i.used.UseChallenge(ctx, parts[2], expiresAt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrChallengeUsed
+	}
+
+	return nil
+}
+
+// Solved reports whether nonce solves challenge.
+func Solved(value, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(value + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+
+	return zeros >= difficulty
+}
+
+// Solve finds nonce of challenge, it is what clients do.
+func Solve(c Challenge) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if Solved(c.Value, nonce, c.Difficulty) {
+			return nonce
+		}
+	}
+}
+
+func (i *Issuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// difficulty adds one bit each time rate doubles over threshold.
+func (i *Issuer) difficulty(rate float64) int {
+	difficulty := i.cfg.Difficulty
+	for limit := float64(i.cfg.Threshold); limit > 0 && rate > limit; limit *= 2 {
+		difficulty++
+	}
+
+	return min(difficulty, i.cfg.MaxDifficulty)
+}
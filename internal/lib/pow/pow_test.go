@@ -0,0 +1,183 @@
+package pow
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestIssuer(t *testing.T) *Issuer {
+	t.Helper()
+
+	return newTestIssuerWith(t, NewMemory())
+}
+
+func newTestIssuerWith(t *testing.T, used Backend) *Issuer {
+	t.Helper()
+
+	i, err := New(Config{
+		Key:           base64.StdEncoding.EncodeToString(make([]byte, keySize)),
+		TTL:           time.Minute,
+		Difficulty:    8,
+		MaxDifficulty: 10,
+		Threshold:     2,
+		Window:        time.Minute,
+	}, used)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		solve   func(i *Issuer, c Challenge) (string, string)
+		at      time.Time
+		wantErr error
+	}{
+		{
+			name: "solved",
+			solve: func(i *Issuer, c Challenge) (string, string) {
+				return c.Value, Solve(c)
+			},
+			at: now,
+		},
+		{
+			name: "wrong nonce",
+			solve: func(i *Issuer, c Challenge) (string, string) {
+				nonce := Solve(c)
+				for Solved(c.Value, nonce, c.Difficulty) {
+					nonce += "x"
+				}
+				return c.Value, nonce
+			},
+			at:      now,
+			wantErr: ErrInvalidSolution,
+		},
+		{
+			name: "lowered difficulty",
+			solve: func(i *Issuer, c Challenge) (string, string) {
+				value := strings.Replace(c.Value, ".8.", ".0.", 1)
+				return value, Solve(Challenge{Value: value})
+			},
+			at:      now,
+			wantErr: ErrInvalidChallenge,
+		},
+		{
+			name: "expired",
+			solve: func(i *Issuer, c Challenge) (string, string) {
+				return c.Value, Solve(c)
+			},
+			at:      now.Add(2 * time.Minute),
+			wantErr: ErrChallengeExpired,
+		},
+		{
+			name: "reused",
+			solve: func(i *Issuer, c Challenge) (string, string) {
+				nonce := Solve(c)
+				if err := i.Verify(context.Background(), c.Value, nonce, now); err != nil {
+					t.Fatal(err)
+				}
+				return c.Value, nonce
+			},
+			at:      now,
+			wantErr: ErrChallengeUsed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := newTestIssuer(t)
+
+			c, err := i.Issue(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, nonce := tt.solve(i, c)
+
+			err = i.Verify(context.Background(), value, nonce, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	used := NewMemory()
+	i := newTestIssuerWith(t, used)
+
+	c, err := i.Issue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := Solve(c)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for n := 0; n < 16; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := i.Verify(ctx, c.Value, nonce, now); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Fatalf("accepted %d times, want once", accepted)
+	}
+
+	// other instance sharing backend rejects it too
+	other := newTestIssuerWith(t, used)
+	if err := other.Verify(ctx, c.Value, nonce, now); !errors.Is(err, ErrChallengeUsed) {
+		t.Fatalf("err = %v, want %v", err, ErrChallengeUsed)
+	}
+}
+
+func TestDifficultyAdapts(t *testing.T) {
+	i := newTestIssuer(t)
+	now := time.Now()
+
+	var got []int
+	for n := 0; n < 8; n++ {
+		c, err := i.Issue(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c.Difficulty)
+	}
+
+	// threshold is 2, so difficulty grows at 3rd and 5th challenge and stops at max
+	want := []int{8, 8, 9, 9, 10, 10, 10, 10}
+	for n := range want {
+		if got[n] != want[n] {
+			t.Fatalf("difficulties = %v, want %v", got, want)
+		}
+	}
+
+	c, err := i.Issue(now.Add(3 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Difficulty != 8 {
+		t.Fatalf("difficulty after quiet period = %d, want 8", c.Difficulty)
+	}
+}
@@ -0,0 +1,42 @@
+package pow
+
+import (
+	"sync"
+	"time"
+)
+
+// rateCounter estimates number of events in the last window by weighting
+// count of previous window by its part still inside the sliding one.
+type rateCounter struct {
+	mu     sync.Mutex
+	window time.Duration
+	start  time.Time
+	cur    int
+	prev   int
+}
+
+func newRateCounter(window time.Duration) *rateCounter {
+	return &rateCounter{window: window}
+}
+
+// add counts event and returns estimated rate including it.
+func (r *rateCounter) add(now time.Time) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.window <= 0 {
+		return 0
+	}
+
+	switch elapsed := now.Sub(r.start); {
+	case elapsed >= 2*r.window:
+		r.start, r.prev, r.cur = now, 0, 0
+	case elapsed >= r.window:
+		r.start, r.prev, r.cur = r.start.Add(r.window), r.cur, 0
+	}
+
+	r.cur++
+
+	prevWeight := 1 - float64(now.Sub(r.start))/float64(r.window)
+	return float64(r.prev)*prevWeight + float64(r.cur)
+}
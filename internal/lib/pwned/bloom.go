@@ -0,0 +1,113 @@
+package pwned
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// bloomMagic starts bloom filter file, it is followed by
+// number of bits and number of hash functions as uint64 and bits.
+const bloomMagic = "PWNDBLM1"
+
+// Bloom is bloom filter of hashes. Passwords not in corpus are reported
+// breached with false positive rate chosen when it is built, breached
+// ones are always reported.
+type Bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// NewBloom returns filter sized for n hashes with false positive rate p.
+func NewBloom(n uint64, p float64) *Bloom {
+	n = max(n, 1)
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	k = max(k, 1)
+
+	return &Bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add adds hash to filter.
+func (b *Bloom) Add(h Hash) {
+	h1, h2 := b.split(h)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *Bloom) Contains(password string) bool {
+	return b.ContainsHash(HashOf(password))
+}
+
+func (b *Bloom) ContainsHash(h Hash) bool {
+	h1, h2 := b.split(h)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// split derives two hashes for double hashing,
+// SHA-1 is uniform already, so its parts are used as is.
+func (b *Bloom) split(h Hash) (uint64, uint64) {
+	h1 := binary.BigEndian.Uint64(h[0:8])
+	h2 := binary.BigEndian.Uint64(h[8:16]) | 1
+	return h1, h2
+}
+
+// WriteTo writes filter in format read by ReadBloom.
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, len(bloomMagic)+16)
+	header = append(header, bloomMagic...)
+	header = binary.LittleEndian.AppendUint64(header, b.m)
+	header = binary.LittleEndian.AppendUint64(header, b.k)
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, b.bits)
+	if err == nil {
+		written += int64(len(b.bits) * 8)
+	}
+	return written, err
+}
+
+// ReadBloom reads filter written by Bloom.WriteTo.
+func ReadBloom(r io.Reader) (*Bloom, error) {
+	header := make([]byte, len(bloomMagic)+16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, ErrInvalidFile
+	}
+
+	m := binary.LittleEndian.Uint64(header[len(bloomMagic):])
+	k := binary.LittleEndian.Uint64(header[len(bloomMagic)+8:])
+	if m == 0 || k == 0 || k > 64 {
+		return nil, ErrInvalidFile
+	}
+
+	b := &Bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+	if err := binary.Read(r, binary.LittleEndian, b.bits); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,109 @@
+// Package pwned checks passwords against local corpus of breached ones.
+// Corpus is list of SHA-1 hashes, e.g. Pwned Passwords "HASH:COUNT" file,
+// or bloom filter of such hashes built by cmd/pwnedbuild.
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidFile = errors.New("invalid breached passwords file")
+
+// Checker tells whether password is breached.
+type Checker interface {
+	Contains(password string) bool
+}
+
+// Hash is SHA-1 of password, corpus is keyed by it.
+type Hash [sha1.Size]byte
+
+func HashOf(password string) Hash {
+	return sha1.Sum([]byte(password))
+}
+
+// ParseHash parses "HASH" or "HASH:COUNT" line of hash list.
+func ParseHash(line string) (Hash, error) {
+	var h Hash
+
+	hexHash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hexHash) != hex.EncodedLen(len(h)) {
+		return h, ErrInvalidFile
+	}
+	if _, err := hex.Decode(h[:], []byte(hexHash)); err != nil {
+		return h, ErrInvalidFile
+	}
+
+	return h, nil
+}
+
+// Load reads bloom filter or hash list, format is told by file header.
+// Whole corpus is kept in memory, so large lists should be built into
+// bloom filter first.
+func Load(path string) (Checker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	header, err := r.Peek(len(bloomMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if bytes.Equal(header, []byte(bloomMagic)) {
+		return ReadBloom(r)
+	}
+	return ReadList(r)
+}
+
+// List is sorted list of hashes.
+type List []Hash
+
+// ReadList reads hash per line, empty lines are skipped.
+func ReadList(r io.Reader) (List, error) {
+	var list List
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		h, err := ParseHash(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		list = append(list, h)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i][:], list[j][:]) < 0
+	})
+
+	return list, nil
+}
+
+func (l List) Contains(password string) bool {
+	h := HashOf(password)
+
+	i := sort.Search(len(l), func(i int) bool {
+		return bytes.Compare(l[i][:], h[:]) >= 0
+	})
+
+	return i < len(l) && l[i] == h
+}
@@ -0,0 +1,71 @@
+package pwned
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var breached = []string{"password", "123456", "qwerty", "letmein"}
+
+func hashList(passwords []string) string {
+	var sb strings.Builder
+	for i, p := range passwords {
+		h := HashOf(p)
+		fmt.Fprintf(&sb, "%s:%d\n", strings.ToUpper(hex.EncodeToString(h[:])), i+1)
+	}
+	return sb.String()
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	listPath := filepath.Join(dir, "list.txt")
+	if err := os.WriteFile(listPath, []byte(hashList(breached)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bloom := NewBloom(uint64(len(breached)), 0.001)
+	for _, p := range breached {
+		bloom.Add(HashOf(p))
+	}
+
+	var buf bytes.Buffer
+	if _, err := bloom.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bloomPath := filepath.Join(dir, "pwned.bloom")
+	if err := os.WriteFile(bloomPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{listPath, bloomPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			checker, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range breached {
+				if !checker.Contains(p) {
+					t.Fatalf("%q is not found", p)
+				}
+			}
+			for _, p := range []string{"correct horse battery staple", "Password"} {
+				if checker.Contains(p) {
+					t.Fatalf("%q is found", p)
+				}
+			}
+		})
+	}
+}
+
+func TestReadListInvalid(t *testing.T) {
+	if _, err := ReadList(strings.NewReader("not a hash\n")); err == nil {
+		t.Fatal("invalid line is accepted")
+	}
+}
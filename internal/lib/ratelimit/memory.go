@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const cleanupInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	// fullAt is when bucket is full again and can be dropped
+	fullAt time.Time
+}
+
+// Memory keeps buckets of one instance in memory.
+type Memory struct {
+	mu          sync.Mutex
+	buckets     map[string]memoryBucket
+	lastCleanup time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:     make(map[string]memoryBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// TakeToken implements Backend.
+func (m *Memory) TakeToken(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	bucket, res := Take(m.buckets[key].Bucket, limit, now)
+
+	missing := float64(limit.Burst) - bucket.Tokens
+	m.buckets[key] = memoryBucket{
+		Bucket: bucket,
+		fullAt: now.Add(time.Duration(missing / float64(limit.Burst) * float64(limit.Period))),
+	}
+
+	if now.Sub(m.lastCleanup) > cleanupInterval {
+		m.cleanup(now)
+	}
+
+	return res, nil
+}
+
+func (m *Memory) cleanup(now time.Time) {
+	for key, b := range m.buckets {
+		if now.After(b.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastCleanup = now
+}
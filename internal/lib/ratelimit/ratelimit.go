@@ -0,0 +1,55 @@
+// Package ratelimit implements token bucket rate limiting
+// with buckets kept in memory or in shared storage.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Burst requests at once and refills Burst tokens per Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Result is outcome of taking token from bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is when next token is available, it is set if not allowed.
+	RetryAfter time.Duration
+}
+
+// Bucket is state of token bucket, zero Bucket is full.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Backend keeps buckets by key.
+type Backend interface {
+	TakeToken(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Take refills bucket for time passed since its update and takes one token.
+func Take(b Bucket, limit Limit, now time.Time) (Bucket, Result) {
+	burst := float64(limit.Burst)
+	rate := burst / limit.Period.Seconds()
+
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = min(burst, b.Tokens+elapsed.Seconds()*rate)
+	}
+	b.UpdatedAt = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return b, Result{Allowed: true, Limit: limit.Burst, Remaining: int(b.Tokens)}
+	}
+
+	retryAfter := time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+	return b, Result{Limit: limit.Burst, RetryAfter: retryAfter}
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	start := time.Date(2024, 4, 27, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		after         time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{name: "first", after: 0, wantAllowed: true, wantRemaining: 2},
+		{name: "second", after: 0, wantAllowed: true, wantRemaining: 1},
+		{name: "third", after: 0, wantAllowed: true, wantRemaining: 0},
+		{name: "empty", after: 0, wantAllowed: false, wantRetry: time.Second},
+		{name: "half refilled", after: 500 * time.Millisecond, wantAllowed: false, wantRetry: 500 * time.Millisecond},
+		{name: "refilled", after: 500 * time.Millisecond, wantAllowed: true, wantRemaining: 0},
+		{name: "capped at burst", after: time.Hour, wantAllowed: true, wantRemaining: 2},
+	}
+
+	var bucket Bucket
+	now := start
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+
+			var res Result
+			bucket, res = Take(bucket, limit, now)
+
+			if res.Allowed != tt.wantAllowed {
+				t.Fatalf("allowed = %v, want %v", res.Allowed, tt.wantAllowed)
+			}
+			if res.Remaining != tt.wantRemaining {
+				t.Fatalf("remaining = %d, want %d", res.Remaining, tt.wantRemaining)
+			}
+			if res.RetryAfter != tt.wantRetry {
+				t.Fatalf("retry after = %s, want %s", res.RetryAfter, tt.wantRetry)
+			}
+		})
+	}
+}
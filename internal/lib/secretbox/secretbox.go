@@ -0,0 +1,66 @@
+// Package secretbox encrypts small secrets stored in database
+// with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+const KeySize = 32
+
+var (
+	ErrInvalidKey        = errors.New("invalid encryption key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns Box with base64 encoded key of KeySize bytes.
+func New(key string) (*Box, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal returns nonce followed by ciphertext.
+func (b *Box) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (b *Box) Open(ciphertext []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
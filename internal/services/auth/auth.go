@@ -5,15 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/cache"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passkey"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passpolicy"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pow"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pwned"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/secretbox"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
@@ -22,8 +30,35 @@ type Auth struct {
 	usrGetter   UserGetter
 	usrPatcher  UserPatcher
 	appProvider AppProvider
-	tokenTTL    time.Duration
-	broker      kafka.Brokerer
+	tknProvider TokenProvider
+	otProvider  OneTimeTokenProvider
+	lcProvider  LoginCodeProvider
+	// attemptProvider counts failed logins for lockout
+	attemptProvider LoginAttemptProvider
+	mfaProvider     MFAProvider
+	pkProvider      PasskeyProvider
+	// secrets encrypts TOTP secrets, it is nil if MFA is not configured
+	secrets  *secretbox.Box
+	passkeys *passkey.WebAuthn
+	// challenges is proof of work, it is nil if it is not required
+	challenges *pow.Issuer
+	hasher     PasswordHasher
+	// policy is default password policy, apps can override its rules
+	policy passpolicy.Policy
+	// breached is corpus of breached passwords, it is nil if not configured
+	breached pwned.Checker
+	// dummyHash is compared with password of unknown user,
+	// so Login does the same work whether user exists or not
+	dummyHash   func() []byte
+	keys        *jwt.Keyring
+	tokenCfg    config.TokenConfig
+	mfaCfg      config.MFAConfig
+	lockoutCfg  config.LockoutConfig
+	passwordCfg config.PasswordConfig
+	revoked     *cache.Cache[string, bool]
+	// introspected caches introspection results by token hash
+	introspected *cache.Cache[string, models.Introspection]
+	broker       kafka.Brokerer
 }
 
 type UserSaver interface {
@@ -33,25 +68,104 @@ type UserSaver interface {
 type UserGetter interface {
 	GetUserByID(ctx context.Context, id int) (models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	PasswordHistory(ctx context.Context, userID int32, limit int) ([][]byte, error)
 }
 
 type UserPatcher interface {
 	PatchUsername(ctx context.Context, user models.User, username string) error
-	PatchPassword(ctx context.Context, user models.User, hashed_password []byte) error
+	PatchPassword(ctx context.Context, user models.User, hashed_password []byte, history int) error
+	PatchPasswordHash(ctx context.Context, user models.User, hashed_password []byte) error
+	PatchEmailVerified(ctx context.Context, user models.User) error
+	PatchEmail(ctx context.Context, user models.User, email string) error
+	PatchPasswordExpiryWarned(ctx context.Context, user models.User, since time.Time) (warned bool, err error)
+}
+
+// PasswordHasher hashes passwords. Rehash is true if password matches
+// hash made by outdated algorithm or parameters.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(password string, hash []byte) (ok, rehash bool, err error)
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int) (models.App, error)
 }
 
+type TokenProvider interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type OneTimeTokenProvider interface {
+	SaveOneTimeToken(ctx context.Context, token models.OneTimeToken) error
+	GetOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error)
+	UseOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error)
+}
+
+type LoginCodeProvider interface {
+	SaveLoginCode(ctx context.Context, code models.LoginCode) error
+	UseLoginCode(ctx context.Context, userID int32, appID int, codeHash []byte, maxAttempts int) error
+}
+
+type LoginAttemptProvider interface {
+	LoginLockedUntil(ctx context.Context, subjects []string) (time.Time, error)
+	AddLoginFailure(ctx context.Context, subject string, since time.Time) (models.LoginFailure, error)
+	LockLogin(ctx context.Context, subject string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, subject string) error
+}
+
+type MFAProvider interface {
+	SaveTOTP(ctx context.Context, totp models.TOTP) error
+	GetTOTP(ctx context.Context, userID int32) (models.TOTP, error)
+	UseTOTPStep(ctx context.Context, userID int32, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int32, codeHashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID int32, codeHash []byte) (left int, err error)
+}
+
+type PasskeyProvider interface {
+	SavePasskey(ctx context.Context, cred models.WebAuthnCredential) error
+	Passkeys(ctx context.Context, userID int32) ([]models.WebAuthnCredential, error)
+	GetPasskey(ctx context.Context, credentialID []byte) (models.WebAuthnCredential, error)
+	UsePasskey(ctx context.Context, cred models.WebAuthnCredential) error
+	SavePasskeySession(ctx context.Context, session models.WebAuthnSession) error
+	UsePasskeySession(ctx context.Context, idHash []byte) (models.WebAuthnSession, error)
+}
+
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserExist          = errors.New("user already exists")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrUserExist             = errors.New("user already exists")
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrTokenReused           = errors.New("refresh token reused")
+	ErrTokenRevoked          = errors.New("token revoked")
+	ErrInvalidAppCredentials = errors.New("invalid app credentials")
+	ErrPermissionDenied      = errors.New("permission denied")
+	ErrTooManyAttempts       = errors.New("too many attempts")
+	ErrEmailNotVerified      = errors.New("email is not verified")
+	ErrMFANotConfigured      = errors.New("mfa is not configured")
+	ErrMFAAlreadyEnabled     = errors.New("mfa is already enabled")
+	ErrMFANotEnrolled        = errors.New("mfa enrollment is not started")
+	ErrPasskeyExist          = errors.New("passkey is already registered")
+	ErrLoginCodeNotAllowed   = errors.New("login code is not allowed for app")
+	ErrChallengeRequired     = errors.New("proof of work is required")
+	ErrChallengeFailed       = errors.New("proof of work is not valid")
+	ErrWeakPassword          = errors.New("password does not match policy")
 )
 
 const (
-	ZeroValue = 0
-	ResetPassword = "reset password"
+	ZeroValue         = 0
+	ResetPassword     = "reset password"
+	EmailVerification = "email verification"
+	ChangeEmail       = "change email"
+	EmailChangeNotice = "email change requested"
+	RecoveryCodeUsed  = "recovery code used"
+	LoginCode         = "login code"
+	// RegistrationAttempt is sent to owner of email somebody registers with again.
+	RegistrationAttempt = "registration attempt"
+	PasswordExpiring    = "password expiring"
 )
 
 func New(
@@ -59,23 +173,63 @@ func New(
 	userGetter UserGetter,
 	userPatcher UserPatcher,
 	appProvider AppProvider,
+	tokenProvider TokenProvider,
+	oneTimeTokenProvider OneTimeTokenProvider,
+	loginCodeProvider LoginCodeProvider,
+	loginAttemptProvider LoginAttemptProvider,
+	mfaProvider MFAProvider,
+	passkeyProvider PasskeyProvider,
+	keys *jwt.Keyring,
+	secrets *secretbox.Box,
+	passkeys *passkey.WebAuthn,
+	challenges *pow.Issuer,
+	hasher PasswordHasher,
+	policy passpolicy.Policy,
+	breached pwned.Checker,
 	log *slog.Logger,
-	tokenTTL time.Duration,
+	tokenCfg config.TokenConfig,
+	mfaCfg config.MFAConfig,
+	lockoutCfg config.LockoutConfig,
+	passwordCfg config.PasswordConfig,
 	broker kafka.Brokerer,
 ) *Auth {
 	return &Auth{
-		usrSaver:    userSaver,
-		usrGetter:   userGetter,
-		usrPatcher:  userPatcher,
-		appProvider: appProvider,
-		log:         log,
-		tokenTTL:    tokenTTL,
-		broker:      broker,
+		usrSaver:        userSaver,
+		usrGetter:       userGetter,
+		usrPatcher:      userPatcher,
+		appProvider:     appProvider,
+		tknProvider:     tokenProvider,
+		otProvider:      oneTimeTokenProvider,
+		lcProvider:      loginCodeProvider,
+		attemptProvider: loginAttemptProvider,
+		mfaProvider:     mfaProvider,
+		pkProvider:      passkeyProvider,
+		secrets:         secrets,
+		passkeys:        passkeys,
+		challenges:      challenges,
+		hasher:          hasher,
+		policy:          policy,
+		breached:        breached,
+		dummyHash: sync.OnceValue(func() []byte {
+			hash, _ := hasher.Hash("dummy password")
+			return hash
+		}),
+		keys:         keys,
+		log:          log,
+		tokenCfg:     tokenCfg,
+		mfaCfg:       mfaCfg,
+		lockoutCfg:   lockoutCfg,
+		passwordCfg:  passwordCfg,
+		revoked:      cache.New[string, bool](),
+		introspected: cache.New[string, models.Introspection](),
+		broker:       broker,
 	}
 }
 
-// Register implements auth.Auth.
-func (a *Auth) Register(ctx context.Context, email, username, password string) (bool, error) {
+// Register implements auth.Auth. Registration with email of existing user
+// succeeds the same way, owner of email is notified instead.
+// Password is checked against policy of app, default one if appID is zero.
+func (a *Auth) Register(ctx context.Context, email, username, password string, appID int) (bool, error) {
 	const op = "services.auth.Register"
 	log := a.log.With(
 		slog.String("op", op),
@@ -84,7 +238,13 @@ func (a *Auth) Register(ctx context.Context, email, username, password string) (
 	)
 	log.Info("register user")
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	err := a.checkPasswordPolicy(ctx, appID, "password", password, models.User{Email: email, Username: username})
+	if err != nil {
+		log.Info("password does not match policy", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPass, err := a.hasher.Hash(password)
 	if err != nil {
 		log.Error("error generating password", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
@@ -98,233 +258,236 @@ func (a *Auth) Register(ctx context.Context, email, username, password string) (
 	); err != nil {
 		if errors.Is(err, storage.ErrUserExist) {
 			log.Info("user already exists", slerr.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrUserExist)
+			return a.notifyRegistrationAttempt(ctx, email, op)
 		}
 		log.Error("error save user", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// user is saved already, verification can be resent on failure
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		log.Error("failed to get user", slerr.Err(err))
+		return true, nil
+	}
+
+	if err := a.sendEmailVerification(ctx, user); err != nil {
+		log.Error("failed to send email verification", slerr.Err(err))
+	}
+
+	return true, nil
+}
+
+// notifyRegistrationAttempt emails owner of registered email. Username
+// taken by another user is reported as is, usernames are public.
+func (a *Auth) notifyRegistrationAttempt(ctx context.Context, email, op string) (bool, error) {
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, fmt.Errorf("%s: %w", op, ErrUserExist)
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  RegistrationAttempt,
+				Message: user.Email,
+			},
+		},
+	)
+
 	return true, nil
 }
 
-// Login implements auth.Auth.
-func (a *Auth) Login(ctx context.Context, email, password string, appID int) (string, error) {
+// Login implements auth.Auth. Failed attempts are counted per user
+// and per client ip, next attempts are delayed and then locked.
+func (a *Auth) Login(ctx context.Context, email, password string, appID int, ip string) (models.TokenPair, error) {
 	const op = "services.auth.Login"
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("email", email),
 		slog.Int("appID", appID),
+		slog.String("ip", ip),
 	)
 	log.Info("login user")
 
-	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
-	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Error("failed to get user", slerr.Err(err))
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
+	var tokens models.TokenPair
+
+	email = strings.ToLower(email)
 
+	user, err := a.usrGetter.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
 		log.Error("failed to get user", slerr.Err(err))
-		return "", fmt.Errorf("%s: %w", op, err)
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+	found := err == nil
+
+	// password of unknown user is compared with dummy hash and password is
+	// checked before lock, so every answer takes the same time
+	hash := a.dummyHash()
+	if found {
+		hash = user.HashedPass
+	}
+	ok, rehash, verifyErr := a.hasher.Verify(password, hash)
+
+	// locked email rejects even the right password, otherwise attacker
+	// could go on guessing from other ips; owner unlocks it by password reset
+	if err := a.checkLoginLock(ctx, email, ip); err != nil {
+		log.Warn("login is locked", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.HashedPass, []byte(password)); err != nil {
-		log.Error("failed to check password", slerr.Err(err))
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	if !found || verifyErr != nil || !ok {
+		if verifyErr != nil {
+			log.Error("failed to check password", slerr.Err(verifyErr))
+		}
+		log.Warn("invalid credentials", slog.Bool("userFound", found))
+		if err := a.loginFailed(ctx, email, ip); err != nil {
+			log.Error("failed to count login failure", slerr.Err(err))
+		}
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if rehash {
+		a.rehashPassword(ctx, user, password)
+	}
+
+	if err := a.loginSucceeded(ctx, email); err != nil {
+		log.Error("failed to reset login failures", slerr.Err(err))
 	}
 
 	app, err := a.appProvider.App(ctx, appID)
 	if err != nil {
 		log.Error("failed to get app", slerr.Err(err))
-		return "", fmt.Errorf("%s: %w", op, err)
+		return tokens, fmt.Errorf("%s: %w", op, err)
 	}
 	if app.Secret == "" {
 		log.Error("empty secret", slerr.Err(ErrInvalidCredentials))
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	token, err := jwt.NewJWTToken(user, app, a.tokenTTL)
+	tokens, err = a.finishLogin(ctx, user, app)
 	if err != nil {
-		log.Error("failed to create token", slerr.Err(err))
-		return "", fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to login", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
-
+	return tokens, nil
 }
 
-// ChangeUsername implements auth.Auth.
-func (a *Auth) ChangeUsername(ctx context.Context, token, username string) (bool, error) {
-	const op = "services.auth.ChangeUsername"
-	log := a.log.With(
-		slog.String("op", op),
-		slog.String("token", token),
-	)
-	log.Info("change username")
-
-	appId, err := jwt.GetAppIDFromJWTToken(token)
-	if err != nil {
-		log.Error("failed to parce app id from token", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
-
-	app, err := a.appProvider.App(ctx, appId)
-	if err != nil {
-		log.Error("failed to get app id", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+// finishLogin issues tokens to user authenticated by first factor.
+// MFA challenge token is returned instead if user has MFA enabled,
+// password change token if password is expired.
+func (a *Auth) finishLogin(ctx context.Context, user models.User, app models.App) (models.TokenPair, error) {
+	var tokens models.TokenPair
 
-	userID, err := jwt.GetSubFromJWTToken(token, app)
-	if err != nil {
-		log.Error("failed to get user id", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
+	if app.RequireVerifiedEmail && !user.IsEmailVerified() {
+		return tokens, ErrEmailNotVerified
 	}
 
-	user, err := a.usrGetter.GetUserByID(ctx, userID)
+	mfa, err := a.mfaEnabled(ctx, user)
 	if err != nil {
-		log.Error("failed to get user from DB", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
+		return tokens, err
 	}
-
-	err = a.usrPatcher.PatchUsername(ctx, user, username)
-	if err != nil {
-		log.Error("failed to patch username", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
+	if mfa {
+		tokens.MFAToken, err = a.issueOneTimeToken(
+			ctx, user, models.PurposeMFAChallenge, strconv.Itoa(app.ID), a.mfaCfg.ChallengeTTL,
+		)
+		return tokens, err
 	}
 
-	return true, nil
+	return a.startSession(ctx, user, app)
 }
 
-// ChangePassword implements auth.Auth.
-func (a *Auth) ChangePassword(ctx context.Context, token, newPassword string) (bool, error) {
-	const op = "services.auth.ChangePassword"
+// rehashPassword replaces outdated hash of user's password.
+// Login goes on if it fails, hash is replaced next time.
+func (a *Auth) rehashPassword(ctx context.Context, user models.User, password string) {
 	log := a.log.With(
-		slog.String("op", op),
-		slog.String("token", token),
+		slog.String("op", "services.auth.rehashPassword"),
+		slog.Int("userID", int(user.ID)),
 	)
-	log.Info("change password")
-
-	appId, err := jwt.GetAppIDFromJWTToken(token)
-	if err != nil {
-		log.Error("failed to parce app id from token", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
 
-	app, err := a.appProvider.App(ctx, appId)
+	hashedPass, err := a.hasher.Hash(password)
 	if err != nil {
-		log.Error("failed to get app id", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to generate password", slerr.Err(err))
+		return
 	}
 
-	userID, err := jwt.GetSubFromJWTToken(token, app)
-	if err != nil {
-		log.Error("failed to get user id", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
+	if err := a.usrPatcher.PatchPasswordHash(ctx, user, hashedPass); err != nil {
+		log.Error("failed to patch password hash", slerr.Err(err))
+		return
 	}
 
-	user, err := a.usrGetter.GetUserByID(ctx, userID)
-	if err != nil {
-		log.Error("failed to get user from DB", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+	log.Info("password is rehashed")
+}
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
-		log.Error("failed to generate password", slerr.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+// ChangeUsername implements auth.Auth.
+func (a *Auth) ChangeUsername(ctx context.Context, principal models.Principal, username string) (bool, error) {
+	const op = "services.auth.ChangeUsername"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("change username")
 
-	err = a.usrPatcher.PatchPassword(ctx, user, hashedPass)
+	err := a.usrPatcher.PatchUsername(ctx, principal.User, username)
 	if err != nil {
-		log.Error("failed to patch password", slerr.Err(err))
+		log.Error("failed to patch username", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return true, nil
 }
 
-// ResetPassword implements auth.Auth.
-func (a *Auth) ResetPassword(ctx context.Context, email string) (bool, error) {
-	const op = "services.auth.ResetPassword"
+// ChangePassword implements auth.Auth.
+func (a *Auth) ChangePassword(ctx context.Context, principal models.Principal, newPassword string) (bool, error) {
+	const op = "services.auth.ChangePassword"
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("email", email),
+		slog.Int("userID", int(principal.User.ID)),
 	)
-	log.Info("reset password")
+	log.Info("change password")
 
-	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	err := a.checkPasswordPolicy(ctx, principal.AppID, "newPassword", newPassword, principal.User)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Error("failed to get user", slerr.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-
-		log.Error("failed to get user", slerr.Err(err))
+		log.Info("password does not match policy", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	password := generatePassword(email)
+	err = a.checkPasswordHistory(ctx, "newPassword", newPassword, principal.User)
+	if err != nil {
+		log.Info("password is used before", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPass, err := a.hasher.Hash(newPassword)
 	if err != nil {
 		log.Error("failed to generate password", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = a.usrPatcher.PatchPassword(ctx, user, hashedPass)
+	err = a.usrPatcher.PatchPassword(ctx, principal.User, hashedPass, a.passwordCfg.History)
 	if err != nil {
 		log.Error("failed to patch password", slerr.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	a.broker.AddToQueue(
-		kafka.KafkaMessage{
-			Topic: "mail",
-			Payload: kafka.Payload{
-				Email: user.Email,
-				Header: ResetPassword,
-				Message: password,
-			},
-		},
-	)
-
 	return true, nil
 }
 
 // Me implements auth.Auth.
-func (a *Auth) Me(ctx context.Context, token string) (models.User, error) {
+// Principal is already loaded from storage when its token was verified.
+func (a *Auth) Me(ctx context.Context, principal models.Principal) (models.User, error) {
 	const op = "services.auth.Me"
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("token", token),
+		slog.Int("userID", int(principal.User.ID)),
 	)
 	log.Info("get me")
 
-	var user models.User
-
-	appId, err := jwt.GetAppIDFromJWTToken(token)
-	if err != nil {
-		log.Error("failed to parce app id from token", slerr.Err(err))
-		return user, fmt.Errorf("%s: %w", op, err)
-	}
-
-	app, err := a.appProvider.App(ctx, appId)
-	if err != nil {
-		log.Error("failed to get app id", slerr.Err(err))
-		return user, fmt.Errorf("%s: %w", op, err)
-	}
-
-	userID, err := jwt.GetSubFromJWTToken(token, app)
-	if err != nil {
-		log.Error("failed to get user id", slerr.Err(err))
-		return user, fmt.Errorf("%s: %w", op, err)
-	}
-
-	user, err = a.usrGetter.GetUserByID(ctx, userID)
-	if err != nil {
-		log.Error("failed to get user from DB", slerr.Err(err))
-		return user, fmt.Errorf("%s: %w", op, err)
-	}
-
-	return user, nil
+	return principal.User, nil
 }
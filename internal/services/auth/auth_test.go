@@ -0,0 +1,404 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/config"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passhash"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passpolicy"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage/memstorage"
+)
+
+const testAppID = 1
+
+// Users and apps are kept by memstorage, other providers are faked
+// with what tested methods need. Methods not
+// overridden panic on the embedded nil interface.
+
+type fakeTokens struct {
+	TokenProvider
+	mu     sync.Mutex
+	tokens []models.RefreshToken
+}
+
+func (p *fakeTokens) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	token.CreatedAt = time.Now().UTC()
+	p.tokens = append(p.tokens, token)
+	return nil
+}
+
+func (p *fakeTokens) GetRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, token := range p.tokens {
+		if bytes.Equal(token.TokenHash, tokenHash) {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, storage.ErrRefreshTokenNotFound
+}
+
+func (p *fakeTokens) UseRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.tokens {
+		if bytes.Equal(p.tokens[i].TokenHash, token.TokenHash) {
+			if p.tokens[i].IsUsed() {
+				return storage.ErrRefreshTokenUsed
+			}
+			p.tokens[i].UsedAt = time.Now().UTC()
+		}
+	}
+	return nil
+}
+
+func (p *fakeTokens) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.tokens {
+		if p.tokens[i].FamilyID == familyID && !p.tokens[i].IsRevoked() {
+			p.tokens[i].RevokedAt = time.Now().UTC()
+		}
+	}
+	return nil
+}
+
+type fakeOneTimeTokens struct {
+	mu     sync.Mutex
+	tokens []models.OneTimeToken
+}
+
+func (p *fakeOneTimeTokens) SaveOneTimeToken(ctx context.Context, token models.OneTimeToken) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	token.CreatedAt = time.Now().UTC()
+	p.tokens = append(p.tokens, token)
+	return nil
+}
+
+func (p *fakeOneTimeTokens) GetOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, token := range p.tokens {
+		if token.Purpose == purpose && bytes.Equal(token.TokenHash, tokenHash) &&
+			token.UsedAt.IsZero() && time.Now().Before(token.ExpiresAt) {
+			return token, nil
+		}
+	}
+	return models.OneTimeToken{}, storage.ErrOneTimeTokenNotFound
+}
+
+func (p *fakeOneTimeTokens) UseOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error) {
+	token, err := p.GetOneTimeToken(ctx, purpose, tokenHash)
+	if err != nil {
+		return token, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.tokens {
+		if bytes.Equal(p.tokens[i].TokenHash, tokenHash) {
+			p.tokens[i].UsedAt = time.Now().UTC()
+		}
+	}
+	return token, nil
+}
+
+type noMFA struct {
+	MFAProvider
+}
+
+func (p noMFA) GetTOTP(ctx context.Context, userID int32) (models.TOTP, error) {
+	return models.TOTP{}, storage.ErrTOTPNotFound
+}
+
+type fakeLoginAttempts struct {
+	mu       sync.Mutex
+	failures map[string]models.LoginFailure
+}
+
+func (p *fakeLoginAttempts) LoginLockedUntil(ctx context.Context, subjects []string) (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var until time.Time
+	for _, subject := range subjects {
+		if locked := p.failures[subject].LockedUntil; locked.After(until) {
+			until = locked
+		}
+	}
+	return until, nil
+}
+
+func (p *fakeLoginAttempts) AddLoginFailure(ctx context.Context, subject string, since time.Time) (models.LoginFailure, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failure := p.failures[subject]
+	if failure.LastFailureAt.Before(since) {
+		failure.Failures = 0
+	}
+	failure.Subject = subject
+	failure.Failures++
+	failure.LastFailureAt = time.Now().UTC()
+	p.failures[subject] = failure
+
+	return failure, nil
+}
+
+func (p *fakeLoginAttempts) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failure := p.failures[subject]
+	failure.LockedUntil = until
+	p.failures[subject] = failure
+
+	return nil
+}
+
+func (p *fakeLoginAttempts) ResetLoginFailures(ctx context.Context, subject string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.failures, subject)
+	return nil
+}
+
+type fakeBroker struct {
+	mu       sync.Mutex
+	messages []kafka.KafkaMessage
+}
+
+func (b *fakeBroker) AddToQueue(msg kafka.KafkaMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.messages = append(b.messages, msg)
+}
+
+func (b *fakeBroker) Stop() {}
+
+// messagesOf returns messages of mail with header sent to email.
+func (b *fakeBroker) messagesOf(email, header string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var messages []string
+	for _, msg := range b.messages {
+		if msg.Payload.Email == email && msg.Payload.Header == header {
+			messages = append(messages, msg.Payload.Message)
+		}
+	}
+	return messages
+}
+
+// headers returns headers of mail sent to email.
+func (b *fakeBroker) headers(email string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var headers []string
+	for _, msg := range b.messages {
+		if msg.Payload.Email == email {
+			headers = append(headers, msg.Payload.Header)
+		}
+	}
+	return headers
+}
+
+var testLockout = config.LockoutConfig{
+	Threshold:   3,
+	IPThreshold: 100,
+	Duration:    15 * time.Minute,
+	Window:      time.Hour,
+}
+
+func newTestAuth(t *testing.T) (*Auth, *memstorage.Storage, *fakeBroker) {
+	t.Helper()
+
+	users := memstorage.New()
+	users.SaveApp(models.App{ID: testAppID, Name: "test app", Secret: "secret"})
+
+	broker := &fakeBroker{}
+	a := New(
+		users,
+		users,
+		users,
+		users,
+		&fakeTokens{},
+		&fakeOneTimeTokens{},
+		nil,
+		&fakeLoginAttempts{failures: make(map[string]models.LoginFailure)},
+		noMFA{},
+		nil,
+		jwt.NewKeyring(nil),
+		nil,
+		nil,
+		nil,
+		passhash.NewSet(passhash.Bcrypt{Cost: bcrypt.MinCost}),
+		passpolicy.Policy{MinLength: 8},
+		nil,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config.TokenConfig{TTL: time.Hour, RefreshTTL: 24 * time.Hour, PasswordResetTTL: time.Hour},
+		config.MFAConfig{},
+		testLockout,
+		config.PasswordConfig{History: 2},
+		broker,
+	)
+
+	return a, users, broker
+}
+
+func register(t *testing.T, a *Auth, email, password string) {
+	t.Helper()
+
+	username, _, _ := strings.Cut(email, "@")
+	if ok, err := a.Register(context.Background(), email, username, password, testAppID); err != nil || !ok {
+		t.Fatalf("Register = %v, %v, want true", ok, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	a, users, broker := newTestAuth(t)
+
+	register(t, a, "Alice@Example.com", "correct horse")
+
+	user, err := users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if string(user.HashedPass) == "correct horse" {
+		t.Error("password is saved as is")
+	}
+	if got := broker.headers(user.Email); len(got) != 1 || got[0] != EmailVerification {
+		t.Errorf("mail = %q, want %q", got, EmailVerification)
+	}
+
+	t.Run("existing email", func(t *testing.T) {
+		ok, err := a.Register(ctx, "alice@example.com", "other", "another password", testAppID)
+		if err != nil || !ok {
+			t.Fatalf("Register = %v, %v, want true", ok, err)
+		}
+		got := broker.headers(user.Email)
+		if len(got) != 2 || got[1] != RegistrationAttempt {
+			t.Errorf("mail = %q, want %q last", got, RegistrationAttempt)
+		}
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		_, err := a.Register(ctx, "bob@example.com", "bob", "short", testAppID)
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Fatalf("Register error = %v, want PasswordPolicyError", err)
+		}
+		if _, err := users.GetUserByEmail(ctx, "bob@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail error = %v, want %v", err, storage.ErrUserNotFound)
+		}
+	})
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	a, _, _ := newTestAuth(t)
+
+	register(t, a, "alice@example.com", "correct horse")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{"ok", "alice@example.com", "correct horse", nil},
+		{"email in other case", "ALICE@example.com", "correct horse", nil},
+		{"wrong password", "alice@example.com", "wrong horse", ErrInvalidCredentials},
+		{"unknown email", "bob@example.com", "correct horse", ErrInvalidCredentials},
+		{"wildcard email", "%@%", "correct horse", ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := a.Login(ctx, tt.email, tt.password, testAppID, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (tokens.AccessToken == "" || tokens.RefreshToken == "") {
+				t.Errorf("Login tokens = %+v, want access and refresh token", tokens)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	a, users, _ := newTestAuth(t)
+
+	register(t, a, "alice@example.com", "correct horse")
+
+	principal := func() models.Principal {
+		user, err := users.GetUserByEmail(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("GetUserByEmail: %v", err)
+		}
+		return models.Principal{User: user, AppID: testAppID}
+	}
+
+	if ok, err := a.ChangePassword(ctx, principal(), "battery staple"); err != nil || !ok {
+		t.Fatalf("ChangePassword = %v, %v, want true", ok, err)
+	}
+
+	if _, err := a.Login(ctx, "alice@example.com", "correct horse", testAppID, ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login with old password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := a.Login(ctx, "alice@example.com", "battery staple", testAppID, ""); err != nil {
+		t.Errorf("Login with new password: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		rule     string
+	}{
+		{"policy", "short", passpolicy.RuleMinLength},
+		{"current password", "battery staple", passpolicy.RuleHistory},
+		{"previous password", "correct horse", passpolicy.RuleHistory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.ChangePassword(ctx, principal(), tt.password)
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("ChangePassword error = %v, want PasswordPolicyError", err)
+			}
+			if policyErr.Violations[0].Rule != tt.rule {
+				t.Errorf("Rule = %q, want %q", policyErr.Violations[0].Rule, tt.rule)
+			}
+		})
+	}
+}
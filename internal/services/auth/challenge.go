@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/pow"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+// GetChallenge issues proof of work challenge for Register and password
+// reset. Empty challenge of zero difficulty means it is not required.
+func (a *Auth) GetChallenge(ctx context.Context) (pow.Challenge, error) {
+	const op = "services.auth.GetChallenge"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if a.challenges == nil {
+		return pow.Challenge{}, nil
+	}
+
+	challenge, err := a.challenges.Issue(time.Now())
+	if err != nil {
+		log.Error("failed to issue challenge", slerr.Err(err))
+		return pow.Challenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return challenge, nil
+}
+
+// CheckChallenge verifies solved challenge, each one is accepted once.
+func (a *Auth) CheckChallenge(ctx context.Context, challenge, nonce string) error {
+	const op = "services.auth.CheckChallenge"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if a.challenges == nil {
+		return nil
+	}
+
+	if challenge == "" {
+		log.Info("challenge is missing")
+		return fmt.Errorf("%s: %w", op, ErrChallengeRequired)
+	}
+
+	if err := a.challenges.Verify(ctx, challenge, nonce, time.Now()); err != nil {
+		log.Warn("failed to verify challenge", slerr.Err(err))
+		return fmt.Errorf("%s: %w: %w", op, ErrChallengeFailed, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// ChangeEmail sends confirmation token to new email and security notice
+// to the current one. Email is changed by ConfirmEmailChange, which also
+// rejects email used by another user.
+func (a *Auth) ChangeEmail(ctx context.Context, principal models.Principal, email string) (bool, error) {
+	const op = "services.auth.ChangeEmail"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("change email")
+
+	email = strings.ToLower(email)
+	if email == principal.User.Email {
+		return true, nil
+	}
+
+	// email used by another user is not reported here, otherwise any user
+	// could check who is registered; ConfirmEmailChange rejects it instead
+	token, err := a.issueOneTimeToken(ctx, principal.User, models.PurposeEmailChange, email, a.tokenCfg.EmailChangeTTL)
+	if err != nil {
+		log.Error("failed to issue email change token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   email,
+				Header:  ChangeEmail,
+				Message: token,
+			},
+		},
+	)
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   principal.User.Email,
+				Header:  EmailChangeNotice,
+				Message: email,
+			},
+		},
+	)
+
+	return true, nil
+}
+
+// ConfirmEmailChange sets email the token was sent to.
+// New email is verified by the token itself.
+func (a *Auth) ConfirmEmailChange(ctx context.Context, token string) (bool, error) {
+	const op = "services.auth.ConfirmEmailChange"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("confirm email change")
+
+	user, ott, err := a.useOneTimeToken(ctx, models.PurposeEmailChange, token)
+	if err != nil {
+		log.Warn("failed to use email change token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	err = a.usrPatcher.PatchEmail(ctx, user, ott.Payload)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExist) {
+			log.Info("email is used by another user", slerr.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrUserExist)
+		}
+		log.Error("failed to patch email", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
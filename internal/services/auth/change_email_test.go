@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+)
+
+// TestChangeEmailTaken checks ChangeEmail answers the same for email of
+// another user, so it can't be used to find registered emails.
+func TestChangeEmailTaken(t *testing.T) {
+	ctx := context.Background()
+	a, users, broker := newTestAuth(t)
+
+	register(t, a, "alice@example.com", "correct horse")
+	register(t, a, "bob@example.com", "correct horse")
+
+	alice, err := users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	principal := models.Principal{User: alice, AppID: testAppID}
+
+	for _, email := range []string{"Bob@example.com", "carol@example.com"} {
+		ok, err := a.ChangeEmail(ctx, principal, email)
+		if err != nil || !ok {
+			t.Fatalf("ChangeEmail(%q) = %v, %v, want true", email, ok, err)
+		}
+	}
+
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		got := broker.headers(email)
+		if len(got) == 0 || got[len(got)-1] != ChangeEmail {
+			t.Errorf("mail to %s = %q, want %q last", email, got, ChangeEmail)
+		}
+	}
+}
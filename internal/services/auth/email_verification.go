@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// VerifyEmail marks email of the user the token was sent to as verified.
+func (a *Auth) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	const op = "services.auth.VerifyEmail"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("verify email")
+
+	user, _, err := a.useOneTimeToken(ctx, models.PurposeEmailVerification, token)
+	if err != nil {
+		log.Warn("failed to use verification token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.usrPatcher.PatchEmailVerified(ctx, user)
+	if err != nil {
+		log.Error("failed to patch email verified", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// ResendVerification sends new verification token, previous one stops working.
+// Nothing is sent if email is verified already or unknown.
+func (a *Auth) ResendVerification(ctx context.Context, email string) (bool, error) {
+	const op = "services.auth.ResendVerification"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+	log.Info("resend verification")
+
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slerr.Err(err))
+			return true, nil
+		}
+
+		log.Error("failed to get user", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.IsEmailVerified() {
+		log.Info("email is verified already")
+		return true, nil
+	}
+
+	if err := a.sendEmailVerification(ctx, user); err != nil {
+		log.Error("failed to send email verification", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (a *Auth) sendEmailVerification(ctx context.Context, user models.User) error {
+	token, err := a.issueOneTimeToken(ctx, user, models.PurposeEmailVerification, "", a.tokenCfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  EmailVerification,
+				Message: token,
+			},
+		},
+	)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// Introspect implements auth.Auth.
+// Caller authenticates with credentials of its app. Invalid, expired or
+// revoked token is not an error, it is reported as inactive (RFC 7662).
+// Results are cached for a short time, so revocation may be noticed with delay.
+func (a *Auth) Introspect(ctx context.Context, appID int, appSecret, token string) (models.Introspection, error) {
+	const op = "services.auth.Introspect"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appID", appID),
+	)
+	log.Info("introspect token")
+
+	var res models.Introspection
+
+	if err := a.authenticateApp(ctx, appID, appSecret); err != nil {
+		log.Warn("failed to authenticate app", slerr.Err(err))
+		return res, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := hex.EncodeToString(opaque.Hash(token))
+	if cached, ok := a.introspected.Get(key); ok {
+		return cached, nil
+	}
+
+	principal, err := a.Authenticate(ctx, token)
+	if err != nil && !isTokenError(err) {
+		log.Error("failed to verify token", slerr.Err(err))
+		return res, fmt.Errorf("%s: %w", op, err)
+	}
+	if err == nil {
+		res = models.Introspection{
+			Active:    true,
+			Sub:       int(principal.User.ID),
+			AppID:     principal.AppID,
+			Username:  principal.User.Username,
+			ExpiresAt: principal.ExpiresAt,
+			IssuedAt:  principal.IssuedAt,
+			Scopes:    principal.Scopes,
+			Roles:     principal.Roles,
+		}
+	}
+
+	ttl := a.tokenCfg.IntrospectionCacheTTL
+	if res.Active && time.Until(res.ExpiresAt) < ttl {
+		ttl = time.Until(res.ExpiresAt)
+	}
+	a.introspected.Set(key, res, ttl)
+
+	return res, nil
+}
+
+// authenticateApp checks app credentials of a downstream service.
+func (a *Auth) authenticateApp(ctx context.Context, appID int, appSecret string) error {
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidAppCredentials
+		}
+		return err
+	}
+
+	if app.Secret == "" || subtle.ConstantTimeCompare([]byte(app.Secret), []byte(appSecret)) != 1 {
+		return ErrInvalidAppCredentials
+	}
+
+	return nil
+}
+
+// isTokenError reports whether err means token is not valid
+// rather than failure of the service.
+func isTokenError(err error) bool {
+	return errors.Is(err, ErrInvalidToken) ||
+		errors.Is(err, ErrTokenRevoked) ||
+		errors.Is(err, storage.ErrAppNotFound) ||
+		errors.Is(err, storage.ErrUserNotFound)
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+// LockedError is returned by Login while it is delayed or locked
+// after failed attempts. It matches ErrTooManyAttempts.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// UnlockAccount forgets failed logins of user with email. Admin only.
+func (a *Auth) UnlockAccount(ctx context.Context, principal models.Principal, email string) (bool, error) {
+	const op = "services.auth.UnlockAccount"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("adminID", int(principal.User.ID)),
+		slog.String("email", email),
+	)
+	log.Info("unlock account")
+
+	if !principal.HasRole(models.RoleAdmin) {
+		log.Warn("caller is not admin")
+		return false, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		log.Error("failed to get user", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.loginSucceeded(ctx, user.Email); err != nil {
+		log.Error("failed to reset login failures", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// checkLoginLock returns LockedError if email or ip is locked.
+// Empty ip is not checked.
+func (a *Auth) checkLoginLock(ctx context.Context, email, ip string) error {
+	lockedUntil, err := a.attemptProvider.LoginLockedUntil(ctx, loginSubjects(email, ip))
+	if err != nil {
+		return err
+	}
+
+	if retryAfter := time.Until(lockedUntil); retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// loginFailed counts failed login of email and ip and delays next one.
+func (a *Auth) loginFailed(ctx context.Context, email, ip string) error {
+	since := time.Now().UTC().Add(-a.lockoutCfg.Window)
+
+	for _, subject := range loginSubjects(email, ip) {
+		failure, err := a.attemptProvider.AddLoginFailure(ctx, subject, since)
+		if err != nil {
+			return err
+		}
+
+		threshold := a.lockoutCfg.Threshold
+		if strings.HasPrefix(subject, models.IPSubject("")) {
+			threshold = a.lockoutCfg.IPThreshold
+		}
+
+		delay := a.loginDelay(failure.Failures, threshold)
+		if delay == 0 {
+			continue
+		}
+
+		if err := a.attemptProvider.LockLogin(ctx, subject, time.Now().UTC().Add(delay)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loginSucceeded forgets failures of email. Failures of ip are kept,
+// otherwise attacker could reset them by logging in to own account.
+// It is also called when user proves owning email by password reset,
+// so account locked by somebody else can be unlocked by its owner.
+func (a *Auth) loginSucceeded(ctx context.Context, email string) error {
+	return a.attemptProvider.ResetLoginFailures(ctx, models.EmailSubject(email))
+}
+
+// loginDelay is how long login is rejected after failures in a row.
+func (a *Auth) loginDelay(failures, threshold int) time.Duration {
+	if threshold > 0 && failures >= threshold {
+		return a.lockoutCfg.Duration
+	}
+	if failures < 2 || a.lockoutCfg.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := a.lockoutCfg.BaseDelay
+	for i := 2; i < failures && delay < a.lockoutCfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, a.lockoutCfg.MaxDelay)
+}
+
+func loginSubjects(email, ip string) []string {
+	subjects := []string{models.EmailSubject(email)}
+	if ip != "" {
+		subjects = append(subjects, models.IPSubject(ip))
+	}
+	return subjects
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLoginLockoutUnknownEmail checks Login answers the same for existing
+// and unknown email, so lockout can't tell which emails are registered.
+func TestLoginLockoutUnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	a, _, _ := newTestAuth(t)
+
+	register(t, a, "alice@example.com", "correct horse")
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{"existing email", "alice@example.com"},
+		{"unknown email", "bob@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < testLockout.Threshold; i++ {
+				_, err := a.Login(ctx, tt.email, "wrong horse", testAppID, "192.0.2.1")
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Login %d error = %v, want %v", i+1, err, ErrInvalidCredentials)
+				}
+			}
+
+			// locked email rejects the right password too
+			_, err := a.Login(ctx, tt.email, "correct horse", testAppID, "192.0.2.1")
+			var locked *LockedError
+			if !errors.As(err, &locked) {
+				t.Fatalf("Login error = %v, want LockedError", err)
+			}
+			if locked.RetryAfter <= testLockout.Duration-time.Minute || locked.RetryAfter > testLockout.Duration {
+				t.Errorf("RetryAfter = %s, want about %s", locked.RetryAfter, testLockout.Duration)
+			}
+		})
+	}
+
+	t.Run("email in other case", func(t *testing.T) {
+		_, err := a.Login(ctx, "ALICE@example.com", "correct horse", testAppID, "")
+		if !errors.Is(err, ErrTooManyAttempts) {
+			t.Fatalf("Login error = %v, want %v", err, ErrTooManyAttempts)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// LoginCodeDigits is length of code sent by RequestLoginCode.
+const LoginCodeDigits = 6
+
+// RequestLoginCode sends numeric login code to user's email.
+// Previously sent code of the same app stops working.
+// Nothing is sent to unknown email, but it succeeds the same way.
+func (a *Auth) RequestLoginCode(ctx context.Context, email string, appID int) (bool, error) {
+	const op = "services.auth.RequestLoginCode"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.Int("appID", appID),
+	)
+	log.Info("request login code")
+
+	app, err := a.loginCodeApp(ctx, appID)
+	if err != nil {
+		log.Warn("failed to get app", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slerr.Err(err))
+			return true, nil
+		}
+
+		log.Error("failed to get user", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := opaque.NewCode(LoginCodeDigits)
+	if err != nil {
+		log.Error("failed to generate login code", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.lcProvider.SaveLoginCode(ctx, models.LoginCode{
+		UserID:    user.ID,
+		AppID:     app.ID,
+		CodeHash:  opaque.Hash(code),
+		ExpiresAt: time.Now().UTC().Add(a.tokenCfg.LoginCodeTTL),
+	})
+	if err != nil {
+		log.Error("failed to save login code", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  LoginCode,
+				Message: code,
+			},
+		},
+	)
+
+	return true, nil
+}
+
+// LoginWithCode exchanges code sent by RequestLoginCode for tokens as Login
+// does. Each wrong code costs one attempt, code is dropped when they run out.
+// Wrong codes are counted with failed logins, so guessing is locked out
+// however many codes are requested.
+func (a *Auth) LoginWithCode(ctx context.Context, email string, appID int, code, ip string) (models.TokenPair, error) {
+	const op = "services.auth.LoginWithCode"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.Int("appID", appID),
+		slog.String("ip", ip),
+	)
+	log.Info("login user with code")
+
+	var tokens models.TokenPair
+
+	app, err := a.loginCodeApp(ctx, appID)
+	if err != nil {
+		log.Warn("failed to get app", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	email = strings.ToLower(email)
+
+	if err := a.checkLoginLock(ctx, email, ip); err != nil {
+		log.Warn("login is locked", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrGetter.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("failed to get user", slerr.Err(err))
+			a.loginCodeFailed(ctx, log, email, ip)
+			return tokens, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+
+		log.Error("failed to get user", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	err = a.lcProvider.UseLoginCode(ctx, user.ID, app.ID, opaque.Hash(code), a.tokenCfg.LoginCodeAttempts)
+	if err != nil {
+		if errors.Is(err, storage.ErrLoginCodeMismatch) || errors.Is(err, storage.ErrLoginCodeNotFound) {
+			log.Warn("failed to use login code", slerr.Err(err))
+			a.loginCodeFailed(ctx, log, email, ip)
+			return tokens, fmt.Errorf("%s: %w: %w", op, ErrInvalidCredentials, err)
+		}
+		log.Error("failed to use login code", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.loginSucceeded(ctx, email); err != nil {
+		log.Error("failed to reset login failures", slerr.Err(err))
+	}
+
+	tokens, err = a.finishLogin(ctx, user, app)
+	if err != nil {
+		log.Error("failed to login", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// loginCodeFailed counts wrong code as failed login,
+// login goes on failing the same way if counting fails.
+func (a *Auth) loginCodeFailed(ctx context.Context, log *slog.Logger, email, ip string) {
+	if err := a.loginFailed(ctx, email, ip); err != nil {
+		log.Error("failed to count login failure", slerr.Err(err))
+	}
+}
+
+// loginCodeApp returns app if it accepts login codes.
+func (a *Auth) loginCodeApp(ctx context.Context, appID int) (models.App, error) {
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		return app, err
+	}
+	if !app.AllowLoginCode {
+		return app, ErrLoginCodeNotAllowed
+	}
+
+	return app, nil
+}
@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/totp"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// BeginTOTPEnrollment creates pending TOTP secret of user and returns it
+// in base32 form and as otpauth URI. Previous pending secret is replaced.
+func (a *Auth) BeginTOTPEnrollment(ctx context.Context, principal models.Principal) (string, string, error) {
+	const op = "services.auth.BeginTOTPEnrollment"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("begin totp enrollment")
+
+	if a.secrets == nil {
+		log.Error("encryption key is not set", slerr.Err(ErrMFANotConfigured))
+		return "", "", fmt.Errorf("%s: %w", op, ErrMFANotConfigured)
+	}
+
+	enabled, err := a.mfaEnabled(ctx, principal.User)
+	if err != nil {
+		log.Error("failed to check mfa", slerr.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if enabled {
+		log.Info("mfa is already enabled")
+		return "", "", fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("failed to generate secret", slerr.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealed, err := a.secrets.Seal(secret)
+	if err != nil {
+		log.Error("failed to encrypt secret", slerr.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.mfaProvider.SaveTOTP(ctx, models.TOTP{
+		UserID: principal.User.ID,
+		Secret: sealed,
+	})
+	if err != nil {
+		log.Error("failed to save totp", slerr.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return totp.Encode(secret), totp.URI(a.mfaCfg.Issuer, principal.User.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment enables MFA if code matches pending secret
+// and returns new recovery codes. They are shown to user only once.
+func (a *Auth) ConfirmTOTPEnrollment(ctx context.Context, principal models.Principal, code string) ([]string, error) {
+	const op = "services.auth.ConfirmTOTPEnrollment"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("confirm totp enrollment")
+
+	enrollment, err := a.mfaProvider.GetTOTP(ctx, principal.User.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			log.Info("totp enrollment is not started")
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+		}
+		log.Error("failed to get totp", slerr.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if enrollment.IsEnabled() {
+		log.Info("mfa is already enabled")
+		return nil, fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+	}
+
+	if err := a.verifyTOTP(ctx, enrollment, code); err != nil {
+		log.Warn("failed to verify totp code", slerr.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codes, err := a.issueRecoveryCodes(ctx, principal.User)
+	if err != nil {
+		log.Error("failed to issue recovery codes", slerr.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of user with new ones.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, principal models.Principal) ([]string, error) {
+	const op = "services.auth.RegenerateRecoveryCodes"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("regenerate recovery codes")
+
+	enabled, err := a.mfaEnabled(ctx, principal.User)
+	if err != nil {
+		log.Error("failed to check mfa", slerr.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !enabled {
+		log.Info("mfa is not enabled")
+		return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnrolled)
+	}
+
+	codes, err := a.issueRecoveryCodes(ctx, principal.User)
+	if err != nil {
+		log.Error("failed to issue recovery codes", slerr.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return codes, nil
+}
+
+// VerifyMFA exchanges challenge token returned by Login and second factor
+// code for access and refresh tokens. Code is TOTP or recovery code.
+func (a *Auth) VerifyMFA(ctx context.Context, mfaToken, code string) (models.TokenPair, error) {
+	const op = "services.auth.VerifyMFA"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("verify mfa")
+
+	var tokens models.TokenPair
+
+	user, challenge, err := a.useOneTimeToken(ctx, models.PurposeMFAChallenge, mfaToken)
+	if err != nil {
+		log.Warn("failed to use mfa challenge", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	if isTOTPCode(code) {
+		enrollment, err := a.mfaProvider.GetTOTP(ctx, user.ID)
+		if err != nil {
+			log.Error("failed to get totp", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := a.verifyTOTP(ctx, enrollment, code); err != nil {
+			log.Warn("failed to verify totp code", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+	} else {
+		if err := a.useRecoveryCode(ctx, user, code); err != nil {
+			log.Warn("failed to use recovery code", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	appID, err := strconv.Atoi(challenge.Payload)
+	if err != nil {
+		log.Error("invalid mfa challenge app", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		log.Error("failed to get app", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err = a.startSession(ctx, user, app)
+	if err != nil {
+		log.Error("failed to create tokens", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+func (a *Auth) mfaEnabled(ctx context.Context, user models.User) (bool, error) {
+	enrollment, err := a.mfaProvider.GetTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return enrollment.IsEnabled(), nil
+}
+
+// verifyTOTP checks code and marks it used. Wrong and reused codes
+// are ErrInvalidCredentials.
+func (a *Auth) verifyTOTP(ctx context.Context, enrollment models.TOTP, code string) error {
+	if a.secrets == nil {
+		return ErrMFANotConfigured
+	}
+
+	secret, err := a.secrets.Open(enrollment.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCredentials
+	}
+
+	err = a.mfaProvider.UseTOTPStep(ctx, enrollment.UserID, step)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPStepUsed) {
+			return fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// issueRecoveryCodes replaces recovery codes of user and returns new ones.
+// Only their hashes are stored.
+func (a *Auth) issueRecoveryCodes(ctx context.Context, user models.User) ([]string, error) {
+	codes := make([]string, 0, a.mfaCfg.RecoveryCodes)
+	hashes := make([][]byte, 0, a.mfaCfg.RecoveryCodes)
+
+	for i := 0; i < a.mfaCfg.RecoveryCodes; i++ {
+		code, err := opaque.NewRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, opaque.Hash(opaque.NormalizeRecoveryCode(code)))
+	}
+
+	if err := a.mfaProvider.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode consumes recovery code and notifies user about it.
+// Unknown and used codes are ErrInvalidCredentials.
+func (a *Auth) useRecoveryCode(ctx context.Context, user models.User, code string) error {
+	hash := opaque.Hash(opaque.NormalizeRecoveryCode(code))
+
+	left, err := a.mfaProvider.UseRecoveryCode(ctx, user.ID, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+		}
+		return err
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  RecoveryCodeUsed,
+				Message: fmt.Sprintf("recovery codes left: %d", left),
+			},
+		},
+	)
+
+	return nil
+}
+
+// isTOTPCode tells TOTP code from recovery code in VerifyMFA.
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// BeginPasskeyRegistration starts passkey registration of user and returns
+// session id and credential creation options for browser.
+func (a *Auth) BeginPasskeyRegistration(ctx context.Context, principal models.Principal) (string, []byte, error) {
+	const op = "services.auth.BeginPasskeyRegistration"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("begin passkey registration")
+
+	creds, err := a.pkProvider.Passkeys(ctx, principal.User.ID)
+	if err != nil {
+		log.Error("failed to get passkeys", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, data, err := a.passkeys.BeginRegistration(principal.User, creds)
+	if err != nil {
+		log.Error("failed to begin registration", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessionID, err := a.savePasskeySession(ctx, principal.User.ID, 0, data)
+	if err != nil {
+		log.Error("failed to save passkey session", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionID, options, nil
+}
+
+// FinishPasskeyRegistration verifies authenticator response and saves passkey.
+func (a *Auth) FinishPasskeyRegistration(ctx context.Context, principal models.Principal, sessionID string, credential []byte) (bool, error) {
+	const op = "services.auth.FinishPasskeyRegistration"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("finish passkey registration")
+
+	session, err := a.usePasskeySession(ctx, sessionID)
+	if err != nil {
+		log.Warn("failed to use passkey session", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.UserID != principal.User.ID {
+		log.Warn("passkey session of another user")
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	creds, err := a.pkProvider.Passkeys(ctx, principal.User.ID)
+	if err != nil {
+		log.Error("failed to get passkeys", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cred, err := a.passkeys.FinishRegistration(principal.User, creds, session.Data, credential)
+	if err != nil {
+		log.Warn("failed to verify passkey", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w: %w", op, ErrInvalidCredentials, err)
+	}
+
+	if err := a.pkProvider.SavePasskey(ctx, cred); err != nil {
+		if errors.Is(err, storage.ErrPasskeyExist) {
+			log.Info("passkey is already registered")
+			return false, fmt.Errorf("%s: %w", op, ErrPasskeyExist)
+		}
+		log.Error("failed to save passkey", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// BeginPasskeyLogin starts login to app with any passkey of any user
+// and returns session id and assertion options for browser.
+func (a *Auth) BeginPasskeyLogin(ctx context.Context, appID int) (string, []byte, error) {
+	const op = "services.auth.BeginPasskeyLogin"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appID", appID),
+	)
+	log.Info("begin passkey login")
+
+	if _, err := a.appProvider.App(ctx, appID); err != nil {
+		log.Error("failed to get app", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, data, err := a.passkeys.BeginLogin()
+	if err != nil {
+		log.Error("failed to begin login", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessionID, err := a.savePasskeySession(ctx, 0, appID, data)
+	if err != nil {
+		log.Error("failed to save passkey session", slerr.Err(err))
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionID, options, nil
+}
+
+// FinishPasskeyLogin verifies assertion and issues tokens as Login does.
+// Passkey verifies user itself, so TOTP is not asked.
+func (a *Auth) FinishPasskeyLogin(ctx context.Context, sessionID string, credential []byte) (models.TokenPair, error) {
+	const op = "services.auth.FinishPasskeyLogin"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("finish passkey login")
+
+	var tokens models.TokenPair
+
+	session, err := a.usePasskeySession(ctx, sessionID)
+	if err != nil {
+		log.Warn("failed to use passkey session", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.AppID == 0 {
+		log.Warn("passkey session is not login session")
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	user, cred, err := a.passkeys.FinishLogin(session.Data, credential, func(credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+		return a.passkeyOwner(ctx, credentialID, userHandle)
+	})
+	if err != nil {
+		log.Warn("failed to verify passkey", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w: %w", op, ErrInvalidCredentials, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	if err := a.pkProvider.UsePasskey(ctx, cred); err != nil {
+		log.Error("failed to update passkey", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.appProvider.App(ctx, session.AppID)
+	if err != nil {
+		log.Error("failed to get app", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if app.RequireVerifiedEmail && !user.IsEmailVerified() {
+		log.Info("email is not verified")
+		return tokens, fmt.Errorf("%s: %w", op, ErrEmailNotVerified)
+	}
+
+	// passkey replaces password and MFA, but not expired password
+	tokens, err = a.startSession(ctx, user, app)
+	if err != nil {
+		log.Error("failed to create tokens", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// passkeyOwner finds user of discoverable credential. User handle returned
+// by authenticator is checked against the user by passkey.FinishLogin.
+func (a *Auth) passkeyOwner(ctx context.Context, credentialID, userHandle []byte) (models.User, []models.WebAuthnCredential, error) {
+	cred, err := a.pkProvider.GetPasskey(ctx, credentialID)
+	if err != nil {
+		return models.User{}, nil, err
+	}
+
+	user, err := a.usrGetter.GetUserByID(ctx, int(cred.UserID))
+	if err != nil {
+		return models.User{}, nil, err
+	}
+
+	creds, err := a.pkProvider.Passkeys(ctx, user.ID)
+	if err != nil {
+		return models.User{}, nil, err
+	}
+
+	return user, creds, nil
+}
+
+func (a *Auth) savePasskeySession(ctx context.Context, userID int32, appID int, data []byte) (string, error) {
+	sessionID, err := opaque.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = a.pkProvider.SavePasskeySession(ctx, models.WebAuthnSession{
+		IDHash:    opaque.Hash(sessionID),
+		UserID:    userID,
+		AppID:     appID,
+		Data:      data,
+		ExpiresAt: time.Now().UTC().Add(a.passkeys.Timeout()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sessionID, nil
+}
+
+// usePasskeySession consumes session, unknown and expired sessions
+// are ErrInvalidToken.
+func (a *Auth) usePasskeySession(ctx context.Context, sessionID string) (models.WebAuthnSession, error) {
+	session, err := a.pkProvider.UsePasskeySession(ctx, opaque.Hash(sessionID))
+	if err != nil {
+		if errors.Is(err, storage.ErrPasskeySessionNotFound) {
+			return session, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return session, err
+	}
+
+	return session, nil
+}
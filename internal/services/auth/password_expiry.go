@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+// expiryWarningInterval is how often user is warned about password expiry.
+const expiryWarningInterval = 24 * time.Hour
+
+// ChangeExpiredPassword sets new password of user whose login returned
+// password change token. New password is checked as by ChangePassword,
+// token stays valid if it does not match policy. User logs in again after.
+func (a *Auth) ChangeExpiredPassword(ctx context.Context, token, newPassword string) (bool, error) {
+	const op = "services.auth.ChangeExpiredPassword"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("change expired password")
+
+	user, ott, err := a.peekOneTimeToken(ctx, models.PurposePasswordChange, token)
+	if err != nil {
+		log.Warn("failed to get password change token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	appID, err := strconv.Atoi(ott.Payload)
+	if err != nil {
+		log.Error("invalid password change token app", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.checkPasswordPolicy(ctx, appID, "newPassword", newPassword, user); err != nil {
+		log.Info("password does not match policy", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkPasswordHistory(ctx, "newPassword", newPassword, user); err != nil {
+		log.Info("password is used before", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPass, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("failed to generate password", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, _, err := a.useOneTimeToken(ctx, models.PurposePasswordChange, token); err != nil {
+		log.Warn("failed to use password change token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.usrPatcher.PatchPassword(ctx, user, hashedPass, a.passwordCfg.History)
+	if err != nil {
+		log.Error("failed to patch password", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// startSession issues tokens to user who passed all factors. Password
+// change token is returned instead if password is expired for the app,
+// user is warned by email when expiry is close.
+func (a *Auth) startSession(ctx context.Context, user models.User, app models.App) (models.TokenPair, error) {
+	var tokens models.TokenPair
+
+	expiresAt := passwordExpiresAt(user, app)
+	if expiresAt.IsZero() {
+		return a.newSession(ctx, user, app)
+	}
+
+	if !time.Now().UTC().Before(expiresAt) {
+		return a.passwordChangeTokens(ctx, user, app)
+	}
+
+	tokens, err := a.newSession(ctx, user, app)
+	if err != nil {
+		return tokens, err
+	}
+
+	a.warnPasswordExpiry(ctx, user, expiresAt)
+
+	return tokens, nil
+}
+
+// passwordChangeTokens returns password change token instead of session.
+func (a *Auth) passwordChangeTokens(ctx context.Context, user models.User, app models.App) (models.TokenPair, error) {
+	var tokens models.TokenPair
+
+	token, err := a.issueOneTimeToken(
+		ctx, user, models.PurposePasswordChange, strconv.Itoa(app.ID), a.tokenCfg.PasswordChangeTTL,
+	)
+	if err != nil {
+		return tokens, err
+	}
+
+	tokens.PasswordChangeToken = token
+	return tokens, nil
+}
+
+// warnPasswordExpiry emails user if password expires within warning days,
+// at most once a day. Warning is marked in storage first, so instances
+// sharing it do not send it twice.
+func (a *Auth) warnPasswordExpiry(ctx context.Context, user models.User, expiresAt time.Time) {
+	warnBefore := time.Duration(a.passwordCfg.ExpiryWarningDays) * 24 * time.Hour
+	if time.Until(expiresAt) > warnBefore {
+		return
+	}
+
+	warned, err := a.usrPatcher.PatchPasswordExpiryWarned(ctx, user, time.Now().UTC().Add(-expiryWarningInterval))
+	if err != nil {
+		a.log.Error("failed to mark password expiry warning",
+			slog.String("op", "services.auth.warnPasswordExpiry"),
+			slog.Int("userID", int(user.ID)),
+			slerr.Err(err),
+		)
+		return
+	}
+	if !warned {
+		return
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  PasswordExpiring,
+				Message: expiresAt.Format(time.RFC3339),
+			},
+		},
+	)
+}
+
+// passwordExpired reports whether password of user is expired for app.
+func passwordExpired(user models.User, app models.App) bool {
+	expiresAt := passwordExpiresAt(user, app)
+	return !expiresAt.IsZero() && !time.Now().UTC().Before(expiresAt)
+}
+
+// passwordExpiresAt returns when password of user expires for app,
+// zero time if app does not expire passwords.
+func passwordExpiresAt(user models.User, app models.App) time.Time {
+	if app.PasswordMaxAgeDays <= 0 {
+		return time.Time{}
+	}
+
+	return user.LastPasswordChange.AddDate(0, 0, app.PasswordMaxAgeDays)
+}
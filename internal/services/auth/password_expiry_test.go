@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+)
+
+func TestLoginPasswordExpiryWarning(t *testing.T) {
+	ctx := context.Background()
+	a, users, broker := newTestAuth(t)
+	a.passwordCfg.ExpiryWarningDays = 7
+
+	// password changed now expires in a day
+	users.SaveApp(models.App{ID: testAppID, Name: "test app", Secret: "secret", PasswordMaxAgeDays: 1})
+	register(t, a, "alice@example.com", "correct horse")
+
+	for i := 0; i < 3; i++ {
+		tokens, err := a.Login(ctx, "alice@example.com", "correct horse", testAppID, "")
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		if tokens.AccessToken == "" || tokens.PasswordChangeToken != "" {
+			t.Fatalf("Login tokens = %+v, want session", tokens)
+		}
+	}
+
+	var warnings int
+	for _, header := range broker.headers("alice@example.com") {
+		if header == PasswordExpiring {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("warnings = %d, want 1", warnings)
+	}
+}
+
+// agedUsers returns users whose password is changed age ago.
+type agedUsers struct {
+	UserGetter
+	age time.Duration
+}
+
+func (u agedUsers) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	user, err := u.UserGetter.GetUserByID(ctx, id)
+	user.LastPasswordChange = user.LastPasswordChange.Add(-u.age)
+	return user, err
+}
+
+func TestRefreshPasswordExpired(t *testing.T) {
+	ctx := context.Background()
+	a, users, _ := newTestAuth(t)
+
+	users.SaveApp(models.App{ID: testAppID, Name: "test app", Secret: "secret", PasswordMaxAgeDays: 1})
+	register(t, a, "alice@example.com", "correct horse")
+
+	tokens, err := a.Login(ctx, "alice@example.com", "correct horse", testAppID, "")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// password expires while session goes on
+	a.usrGetter = agedUsers{UserGetter: users, age: 2 * 24 * time.Hour}
+
+	refreshed, err := a.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if refreshed.AccessToken != "" || refreshed.RefreshToken != "" || refreshed.PasswordChangeToken == "" {
+		t.Fatalf("Refresh tokens = %+v, want password change token only", refreshed)
+	}
+
+	stored, err := a.tknProvider.GetRefreshToken(ctx, opaque.Hash(tokens.RefreshToken))
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if !stored.IsRevoked() {
+		t.Error("refresh token family is not revoked")
+	}
+
+	if _, err := a.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh again error = %v, want %v", err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passpolicy"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+// PasswordPolicyError lists rules new password breaks, Field is request
+// field of the password. It matches ErrWeakPassword.
+type PasswordPolicyError struct {
+	Field      string
+	Violations []passpolicy.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %d rules broken", ErrWeakPassword, len(e.Violations))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// checkPasswordPolicy checks password of user against policy of app,
+// default policy is used if app does not override it. Unknown app is
+// storage.ErrAppNotFound, so client can't skip policy of its app.
+// Password found in breach corpus is rejected by any policy.
+func (a *Auth) checkPasswordPolicy(ctx context.Context, appID int, field, password string, user models.User) error {
+	policy, err := a.passwordPolicy(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	violations := policy.Check(password, user.Email, user.Username)
+	if a.breached != nil && a.breached.Contains(password) {
+		violations = append(violations, passpolicy.Violation{
+			Rule:        passpolicy.RuleBreached,
+			Description: "password is found in data breach",
+		})
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Field: field, Violations: violations}
+	}
+
+	return nil
+}
+
+// checkPasswordHistory rejects current password of user
+// and passwords kept in history.
+func (a *Auth) checkPasswordHistory(ctx context.Context, field, password string, user models.User) error {
+	if a.passwordCfg.History <= 0 {
+		return nil
+	}
+
+	history, err := a.usrGetter.PasswordHistory(ctx, user.ID, a.passwordCfg.History)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range append([][]byte{user.HashedPass}, history...) {
+		ok, _, err := a.hasher.Verify(password, hash)
+		if err != nil {
+			a.log.Warn("failed to verify previous password", slerr.Err(err))
+			continue
+		}
+		if ok {
+			return &PasswordPolicyError{
+				Field: field,
+				Violations: []passpolicy.Violation{{
+					Rule:        passpolicy.RuleHistory,
+					Description: fmt.Sprintf("password must differ from last %d passwords", a.passwordCfg.History+1),
+				}},
+			}
+		}
+	}
+
+	return nil
+}
+
+// passwordPolicy returns default policy with rules set by app on top of it.
+func (a *Auth) passwordPolicy(ctx context.Context, appID int) (passpolicy.Policy, error) {
+	policy := a.policy
+	policy.BannedWords = append([]string(nil), a.policy.BannedWords...)
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		return policy, err
+	}
+
+	if len(app.PasswordPolicy) > 0 {
+		if err := json.Unmarshal(app.PasswordPolicy, &policy); err != nil {
+			return policy, fmt.Errorf("invalid password policy of app %d: %w", appID, err)
+		}
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/app/kafka"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// RequestPasswordReset sends single use reset token to user's email.
+// Password is not changed until the token is confirmed.
+// It succeeds for unknown email too, not to tell whether user exists.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string, appID int) (bool, error) {
+	const op = "services.auth.RequestPasswordReset"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.Int("appID", appID),
+	)
+	log.Info("request password reset")
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		log.Warn("failed to get app", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrGetter.GetUserByEmail(ctx, strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slerr.Err(err))
+			return true, nil
+		}
+
+		log.Error("failed to get user", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// new password is checked against policy of app reset is requested for
+	token, err := a.issueOneTimeToken(ctx, user, models.PurposePasswordReset, strconv.Itoa(app.ID), a.tokenCfg.PasswordResetTTL)
+	if err != nil {
+		log.Error("failed to issue reset token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.broker.AddToQueue(
+		kafka.KafkaMessage{
+			Topic: "mail",
+			Payload: kafka.Payload{
+				Email:   user.Email,
+				Header:  ResetPassword,
+				Message: token,
+			},
+		},
+	)
+
+	return true, nil
+}
+
+// ConfirmPasswordReset sets new password of the user the token was sent to.
+// Tokens issued before are rejected as for ChangePassword. Token stays valid
+// if password does not match policy of app the reset is requested for,
+// so user can try another one.
+func (a *Auth) ConfirmPasswordReset(ctx context.Context, token, newPassword string) (bool, error) {
+	const op = "services.auth.ConfirmPasswordReset"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("confirm password reset")
+
+	user, ott, err := a.peekOneTimeToken(ctx, models.PurposePasswordReset, token)
+	if err != nil {
+		log.Warn("failed to get reset token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int("userID", int(user.ID)))
+
+	appID, err := strconv.Atoi(ott.Payload)
+	if err != nil {
+		log.Warn("invalid reset token app", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.checkPasswordPolicy(ctx, appID, "new_password", newPassword, user); err != nil {
+		log.Info("password does not match policy", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPass, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("failed to generate password", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, _, err := a.useOneTimeToken(ctx, models.PurposePasswordReset, token); err != nil {
+		log.Warn("failed to use reset token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.usrPatcher.PatchPassword(ctx, user, hashedPass, a.passwordCfg.History)
+	if err != nil {
+		log.Error("failed to patch password", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// owner of email is proven, login locked by somebody else is unlocked
+	if err := a.loginSucceeded(ctx, user.Email); err != nil {
+		log.Error("failed to reset login failures", slerr.Err(err))
+	}
+
+	return true, nil
+}
+
+// issueOneTimeToken saves hash of new token for user and returns the token.
+// Payload is kept with the token, e.g. new email for email change.
+func (a *Auth) issueOneTimeToken(ctx context.Context, user models.User, purpose, payload string, ttl time.Duration) (string, error) {
+	token, err := opaque.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = a.otProvider.SaveOneTimeToken(ctx, models.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Payload:   payload,
+		TokenHash: opaque.Hash(token),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useOneTimeToken consumes token and returns its owner.
+// Unknown, used and expired tokens are ErrInvalidToken.
+func (a *Auth) useOneTimeToken(ctx context.Context, purpose, token string) (models.User, models.OneTimeToken, error) {
+	ott, err := a.otProvider.UseOneTimeToken(ctx, purpose, opaque.Hash(token))
+	return a.oneTimeTokenOwner(ctx, ott, err)
+}
+
+// peekOneTimeToken is useOneTimeToken that leaves token valid,
+// e.g. to check request before token is consumed.
+func (a *Auth) peekOneTimeToken(ctx context.Context, purpose, token string) (models.User, models.OneTimeToken, error) {
+	ott, err := a.otProvider.GetOneTimeToken(ctx, purpose, opaque.Hash(token))
+	return a.oneTimeTokenOwner(ctx, ott, err)
+}
+
+func (a *Auth) oneTimeTokenOwner(ctx context.Context, ott models.OneTimeToken, err error) (models.User, models.OneTimeToken, error) {
+	if err != nil {
+		if errors.Is(err, storage.ErrOneTimeTokenNotFound) {
+			return models.User{}, ott, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return models.User{}, ott, err
+	}
+
+	user, err := a.usrGetter.GetUserByID(ctx, int(ott.UserID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ott, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return models.User{}, ott, err
+	}
+
+	return user, ott, nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/passpolicy"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+const strictAppID = 2
+
+// TestConfirmPasswordResetAppPolicy checks new password is checked against
+// policy of app the reset is requested for, not one chosen by client.
+func TestConfirmPasswordResetAppPolicy(t *testing.T) {
+	ctx := context.Background()
+	a, users, broker := newTestAuth(t)
+
+	users.SaveApp(models.App{ID: strictAppID, Name: "strict app", Secret: "secret", PasswordPolicy: []byte(`{"min_length":20}`)})
+	register(t, a, "alice@example.com", "correct horse")
+
+	if ok, err := a.RequestPasswordReset(ctx, "alice@example.com", strictAppID); err != nil || !ok {
+		t.Fatalf("RequestPasswordReset = %v, %v, want true", ok, err)
+	}
+	tokens := broker.messagesOf("alice@example.com", ResetPassword)
+	if len(tokens) != 1 {
+		t.Fatalf("reset mail = %q, want one", tokens)
+	}
+
+	_, err := a.ConfirmPasswordReset(ctx, tokens[0], "battery staple")
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("ConfirmPasswordReset error = %v, want PasswordPolicyError", err)
+	}
+	if policyErr.Violations[0].Rule != passpolicy.RuleMinLength {
+		t.Errorf("Rule = %q, want %q", policyErr.Violations[0].Rule, passpolicy.RuleMinLength)
+	}
+
+	// token stays valid after policy error
+	if ok, err := a.ConfirmPasswordReset(ctx, tokens[0], "correct horse battery staple"); err != nil || !ok {
+		t.Fatalf("ConfirmPasswordReset = %v, %v, want true", ok, err)
+	}
+	if _, err := a.Login(ctx, "alice@example.com", "correct horse battery staple", strictAppID, ""); err != nil {
+		t.Errorf("Login with new password: %v", err)
+	}
+}
+
+func TestPasswordPolicyUnknownApp(t *testing.T) {
+	ctx := context.Background()
+	a, _, _ := newTestAuth(t)
+
+	for _, appID := range []int{0, 42} {
+		if _, err := a.Register(ctx, "alice@example.com", "alice", "correct horse", appID); !errors.Is(err, storage.ErrAppNotFound) {
+			t.Errorf("Register app %d error = %v, want %v", appID, err, storage.ErrAppNotFound)
+		}
+		if _, err := a.RequestPasswordReset(ctx, "alice@example.com", appID); !errors.Is(err, storage.ErrAppNotFound) {
+			t.Errorf("RequestPasswordReset app %d error = %v, want %v", appID, err, storage.ErrAppNotFound)
+		}
+	}
+}
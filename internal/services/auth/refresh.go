@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// Refresh implements auth.Auth.
+// Refresh token can be exchanged only once. If used token is presented again,
+// the whole family is revoked, so both the thief and the owner must login again.
+// Expired password revokes the family too, password change token is returned.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	const op = "services.auth.Refresh"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("refresh token")
+
+	var tokens models.TokenPair
+
+	stored, err := a.tknProvider.GetRefreshToken(ctx, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("unknown refresh token", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		log.Error("failed to get refresh token", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(
+		slog.Int("userID", int(stored.UserID)),
+		slog.String("family", stored.FamilyID),
+	)
+
+	if stored.IsRevoked() || stored.IsExpired(time.Now().UTC()) {
+		log.Warn("refresh token is revoked or expired")
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if stored.IsUsed() {
+		return tokens, a.revokeFamily(ctx, log, op, stored.FamilyID)
+	}
+
+	if err := a.tknProvider.UseRefreshToken(ctx, stored); err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenUsed) {
+			return tokens, a.revokeFamily(ctx, log, op, stored.FamilyID)
+		}
+		log.Error("failed to use refresh token", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrGetter.GetUserByID(ctx, int(stored.UserID))
+	if err != nil {
+		log.Error("failed to get user from DB", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// refresh tokens issued before password change are logged out too
+	if stored.CreatedAt.Before(user.LastPasswordChange) {
+		log.Warn("refresh token issued before last password change")
+		if err := a.tknProvider.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			log.Error("failed to revoke token family", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+		return tokens, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	app, err := a.appProvider.App(ctx, stored.AppID)
+	if err != nil {
+		log.Error("failed to get app", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// password expired during session is changed before session goes on,
+	// otherwise rotation would keep it alive forever
+	if passwordExpired(user, app) {
+		log.Info("password is expired, revoke family")
+		if err := a.tknProvider.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			log.Error("failed to revoke token family", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+
+		tokens, err = a.passwordChangeTokens(ctx, user, app)
+		if err != nil {
+			log.Error("failed to issue password change token", slerr.Err(err))
+			return tokens, fmt.Errorf("%s: %w", op, err)
+		}
+		return tokens, nil
+	}
+
+	tokens, err = a.issueTokens(ctx, user, app, stored.FamilyID)
+	if err != nil {
+		log.Error("failed to create tokens", slerr.Err(err))
+		return tokens, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+func (a *Auth) revokeFamily(ctx context.Context, log *slog.Logger, op, familyID string) error {
+	log.Warn("refresh token reuse detected, revoke family")
+
+	if err := a.tknProvider.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		log.Error("failed to revoke token family", slerr.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrTokenReused)
+}
+
+// newSession issues tokens of new refresh token family.
+func (a *Auth) newSession(ctx context.Context, user models.User, app models.App) (models.TokenPair, error) {
+	familyID, err := opaque.NewID()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return a.issueTokens(ctx, user, app, familyID)
+}
+
+// issueTokens creates access token and refresh token of given family.
+func (a *Auth) issueTokens(ctx context.Context, user models.User, app models.App, familyID string) (models.TokenPair, error) {
+	var tokens models.TokenPair
+
+	accessToken, err := jwt.NewJWTToken(user, app, a.tokenCfg.TTL, a.keys)
+	if err != nil {
+		return tokens, err
+	}
+
+	refreshToken, err := opaque.NewToken()
+	if err != nil {
+		return tokens, err
+	}
+
+	if err := a.tknProvider.SaveRefreshToken(
+		ctx,
+		models.RefreshToken{
+			UserID:    user.ID,
+			AppID:     app.ID,
+			FamilyID:  familyID,
+			TokenHash: opaque.Hash(refreshToken),
+			ExpiresAt: time.Now().UTC().Add(a.tokenCfg.RefreshTTL),
+		},
+	); err != nil {
+		return tokens, err
+	}
+
+	tokens.AccessToken = accessToken
+	tokens.RefreshToken = refreshToken
+
+	return tokens, nil
+}
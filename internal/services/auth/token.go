@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/opaque"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// Logout implements auth.Auth.
+// Access token is added to denylist, refresh token family is revoked.
+func (a *Auth) Logout(ctx context.Context, principal models.Principal, refreshToken string) (bool, error) {
+	const op = "services.auth.Logout"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("userID", int(principal.User.ID)),
+	)
+	log.Info("logout")
+
+	if err := a.revokeAccessToken(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+		log.Error("failed to revoke token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refreshToken == "" {
+		return true, nil
+	}
+
+	stored, err := a.tknProvider.GetRefreshToken(ctx, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("unknown refresh token", slerr.Err(err))
+			return true, nil
+		}
+		log.Error("failed to get refresh token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// refresh token of another user must not be revoked by this one
+	if stored.UserID != principal.User.ID {
+		log.Warn("refresh token belongs to another user")
+		return true, nil
+	}
+
+	if err := a.tknProvider.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		log.Error("failed to revoke token family", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// RevokeToken implements auth.Auth.
+// Accepts both access and refresh tokens.
+func (a *Auth) RevokeToken(ctx context.Context, token string) (bool, error) {
+	const op = "services.auth.RevokeToken"
+	log := a.log.With(
+		slog.String("op", op),
+	)
+	log.Info("revoke token")
+
+	if !isJWT(token) {
+		stored, err := a.tknProvider.GetRefreshToken(ctx, opaque.Hash(token))
+		if err != nil {
+			if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+				log.Warn("unknown refresh token", slerr.Err(err))
+				return false, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+			}
+			log.Error("failed to get refresh token", slerr.Err(err))
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := a.tknProvider.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			log.Error("failed to revoke token family", slerr.Err(err))
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return true, nil
+	}
+
+	claims, err := a.parseToken(ctx, token)
+	if err != nil {
+		log.Error("failed to parse token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.revokeAccessToken(ctx, claims.JTI, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke token", slerr.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// Authenticate implements auth.Authenticator.
+// Verifies access token and returns its owner.
+// Tokens issued before the last password change are rejected,
+// so changing password logs the user out everywhere.
+func (a *Auth) Authenticate(ctx context.Context, token string) (models.Principal, error) {
+	const op = "services.auth.Authenticate"
+
+	var principal models.Principal
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return principal, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrGetter.GetUserByID(ctx, claims.Sub)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return principal, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+		}
+		return principal, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := jwt.CheckIssuedAt(claims, user.LastPasswordChange); err != nil {
+		return principal, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	principal = models.Principal{
+		User:      user,
+		AppID:     claims.AppID,
+		TokenID:   claims.JTI,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+		Scopes:    claims.Scopes,
+		Roles:     claims.Roles,
+	}
+
+	return principal, nil
+}
+
+// verifyToken checks token signature, expiration and revocation.
+func (a *Auth) verifyToken(ctx context.Context, token string) (jwt.Claims, error) {
+	claims, err := a.parseToken(ctx, token)
+	if err != nil {
+		return claims, err
+	}
+
+	revoked, err := a.isTokenRevoked(ctx, claims)
+	if err != nil {
+		return claims, err
+	}
+	if revoked {
+		return claims, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// parseToken checks token signature and expiration with secret of the app from token.
+func (a *Auth) parseToken(ctx context.Context, token string) (jwt.Claims, error) {
+	var claims jwt.Claims
+
+	appID, err := jwt.GetAppIDFromJWTToken(token)
+	if err != nil {
+		return claims, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		return claims, err
+	}
+
+	claims, err = jwt.ParseJWTToken(token, app, a.keys)
+	if err != nil {
+		return claims, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+func (a *Auth) isTokenRevoked(ctx context.Context, claims jwt.Claims) (bool, error) {
+	// tokens issued before jti was introduced can not be revoked
+	if claims.JTI == "" {
+		return false, nil
+	}
+
+	if revoked, ok := a.revoked.Get(claims.JTI); ok {
+		return revoked, nil
+	}
+
+	revoked, err := a.tknProvider.IsTokenRevoked(ctx, claims.JTI)
+	if err != nil {
+		return false, err
+	}
+
+	if revoked {
+		a.revoked.Set(claims.JTI, true, time.Until(claims.ExpiresAt))
+	} else {
+		a.revoked.Set(claims.JTI, false, a.tokenCfg.RevokedCacheTTL)
+	}
+
+	return revoked, nil
+}
+
+func (a *Auth) revokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return ErrInvalidToken
+	}
+
+	if err := a.tknProvider.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	a.revoked.Set(jti, true, time.Until(expiresAt))
+
+	return nil
+}
+
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// GetJWKS implements auth.Auth.
+// Returns public keys, so other services can verify tokens without app secret.
+func (a *Auth) GetJWKS(ctx context.Context) (jwt.JWKS, error) {
+	return a.keys.JWKS(), nil
+}
@@ -0,0 +1,300 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/secretbox"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/slerr"
+)
+
+// Keys keeps keyring in sync with signing_keys table.
+//
+// Key lifecycle: pending -> active -> retiring -> retired.
+// Pending key is published in JWKS before it signs anything, so downstream
+// caches know it in advance. Retiring key still verifies tokens until the
+// longest token TTL has passed since rotation.
+// Private keys are stored encrypted.
+type Keys struct {
+	log         *slog.Logger
+	keyProvider KeyProvider
+	keyring     *jwt.Keyring
+	fallback    *jwt.SigningKey
+	// secrets encrypts private keys, keys can't be added without it
+	secrets *secretbox.Box
+	maxTTL  time.Duration
+	// activationDelay is how long key stays pending, so every instance
+	// and JWKS cache knows it before it signs tokens
+	activationDelay time.Duration
+}
+
+type KeyProvider interface {
+	SaveSigningKey(ctx context.Context, key models.SigningKey) error
+	SigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	ActivateSigningKey(ctx context.Context, kid string) error
+	RetireSigningKeys(ctx context.Context, before time.Time) (int64, error)
+}
+
+var (
+	ErrInvalidAlgorithm = errors.New("invalid signing algorithm")
+	ErrNotConfigured    = errors.New("signing keys encryption key is not configured")
+	ErrKeyNotReady      = errors.New("pending key is not published long enough")
+)
+
+// pemPrefix starts private keys stored before they were encrypted.
+const pemPrefix = "-----BEGIN"
+
+// New creates keys service. Fallback key (from config) signs tokens while
+// there is no active key in storage.
+func New(
+	log *slog.Logger,
+	keyProvider KeyProvider,
+	keyring *jwt.Keyring,
+	fallback *jwt.SigningKey,
+	secrets *secretbox.Box,
+	maxTTL time.Duration,
+	activationDelay time.Duration,
+) *Keys {
+	return &Keys{
+		log:             log,
+		keyProvider:     keyProvider,
+		keyring:         keyring,
+		fallback:        fallback,
+		secrets:         secrets,
+		maxTTL:          maxTTL,
+		activationDelay: activationDelay,
+	}
+}
+
+// Reload loads keys from storage into keyring.
+func (k *Keys) Reload(ctx context.Context) error {
+	const op = "services.keys.Reload"
+
+	stored, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	var (
+		active   *jwt.SigningKey
+		accepted []jwt.SigningKey
+	)
+
+	if k.fallback != nil {
+		accepted = append(accepted, *k.fallback)
+	}
+
+	for _, s := range stored {
+		if s.State == models.SigningKeyRetiring && now.After(s.RetiringAt.Add(k.maxTTL)) {
+			continue
+		}
+
+		key, err := k.privateKey(s)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, s.ID, err)
+		}
+
+		if s.State == models.SigningKeyActive {
+			active = &key
+		}
+		accepted = append(accepted, key)
+	}
+
+	if active == nil {
+		active = k.fallback
+	}
+
+	k.keyring.Set(active, accepted...)
+
+	return nil
+}
+
+// Watch reloads keys periodically until ctx is done,
+// so rotation made by authctl is picked up without restart.
+func (k *Keys) Watch(ctx context.Context, interval time.Duration) {
+	const op = "services.keys.Watch"
+	log := k.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				log.Error("failed to reload signing keys", slerr.Err(err))
+			}
+		}
+	}
+}
+
+// Add generates new pending key.
+func (k *Keys) Add(ctx context.Context, alg string) (models.SigningKey, error) {
+	const op = "services.keys.Add"
+	log := k.log.With(
+		slog.String("op", op),
+		slog.String("alg", alg),
+	)
+	log.Info("add signing key")
+
+	var stored models.SigningKey
+
+	if k.secrets == nil {
+		return stored, fmt.Errorf("%s: %w", op, ErrNotConfigured)
+	}
+
+	key, err := jwt.GenerateSigningKey(alg)
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalidKey) {
+			return stored, fmt.Errorf("%s: %w", op, ErrInvalidAlgorithm)
+		}
+		log.Error("failed to generate key", slerr.Err(err))
+		return stored, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := jwt.MarshalPrivateKey(key)
+	if err != nil {
+		log.Error("failed to marshal key", slerr.Err(err))
+		return stored, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err = k.secrets.Seal(data)
+	if err != nil {
+		log.Error("failed to encrypt key", slerr.Err(err))
+		return stored, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored = models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: data,
+		State:      models.SigningKeyPending,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := k.keyProvider.SaveSigningKey(ctx, stored); err != nil {
+		log.Error("failed to save key", slerr.Err(err))
+		return stored, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stored, nil
+}
+
+// Rotate activates the oldest pending key, current active key becomes
+// retiring. Keys retiring longer than max token TTL are retired.
+// Key must be pending for activation delay, so other instances and JWKS
+// caches know it. Otherwise ErrKeyNotReady is returned with the pending key,
+// new one is added if there is none, and rotation is run again later.
+func (k *Keys) Rotate(ctx context.Context, alg string) (models.SigningKey, error) {
+	const op = "services.keys.Rotate"
+	log := k.log.With(
+		slog.String("op", op),
+	)
+	log.Info("rotate signing keys")
+
+	var next models.SigningKey
+
+	stored, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		log.Error("failed to get keys", slerr.Err(err))
+		return next, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, s := range stored {
+		if s.State == models.SigningKeyPending {
+			next = s
+			break
+		}
+	}
+
+	if next.ID == "" {
+		next, err = k.Add(ctx, alg)
+		if err != nil {
+			return next, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if time.Since(next.CreatedAt) < k.activationDelay {
+		log.Info("pending key is not ready", slog.String("kid", next.ID))
+		return next, fmt.Errorf("%s: %w", op, ErrKeyNotReady)
+	}
+
+	if err := k.keyProvider.ActivateSigningKey(ctx, next.ID); err != nil {
+		log.Error("failed to activate key", slerr.Err(err))
+		return next, fmt.Errorf("%s: %w", op, err)
+	}
+	next.State = models.SigningKeyActive
+
+	if _, err := k.Retire(ctx); err != nil {
+		return next, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("signing key activated", slog.String("kid", next.ID))
+
+	return next, nil
+}
+
+// Retire marks keys retiring longer than max token TTL as retired.
+func (k *Keys) Retire(ctx context.Context) (int64, error) {
+	const op = "services.keys.Retire"
+	log := k.log.With(
+		slog.String("op", op),
+	)
+
+	retired, err := k.keyProvider.RetireSigningKeys(ctx, time.Now().UTC().Add(-k.maxTTL))
+	if err != nil {
+		log.Error("failed to retire keys", slerr.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("signing keys retired", slog.Int64("count", retired))
+
+	return retired, nil
+}
+
+// ActivatesAt returns when pending key can be activated by Rotate.
+func (k *Keys) ActivatesAt(key models.SigningKey) time.Time {
+	return key.CreatedAt.Add(k.activationDelay)
+}
+
+// privateKey decrypts stored key. Keys stored before encryption
+// was added are plain PEM and are read as is.
+func (k *Keys) privateKey(stored models.SigningKey) (jwt.SigningKey, error) {
+	data := stored.PrivateKey
+
+	if !bytes.HasPrefix(data, []byte(pemPrefix)) {
+		if k.secrets == nil {
+			return jwt.SigningKey{}, ErrNotConfigured
+		}
+
+		var err error
+		data, err = k.secrets.Open(data)
+		if err != nil {
+			return jwt.SigningKey{}, err
+		}
+	}
+
+	return jwt.ParsePrivateKey(data)
+}
+
+// List returns all not retired keys.
+func (k *Keys) List(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "services.keys.List"
+
+	keys, err := k.keyProvider.SigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,121 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/jwt"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/secretbox"
+)
+
+type fakeKeyProvider struct {
+	keys []models.SigningKey
+}
+
+func (p *fakeKeyProvider) SaveSigningKey(ctx context.Context, key models.SigningKey) error {
+	p.keys = append(p.keys, key)
+	return nil
+}
+
+func (p *fakeKeyProvider) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	return append([]models.SigningKey(nil), p.keys...), nil
+}
+
+func (p *fakeKeyProvider) ActivateSigningKey(ctx context.Context, kid string) error {
+	for i := range p.keys {
+		switch {
+		case p.keys[i].State == models.SigningKeyActive:
+			p.keys[i].State = models.SigningKeyRetiring
+			p.keys[i].RetiringAt = time.Now().UTC()
+		case p.keys[i].ID == kid:
+			p.keys[i].State = models.SigningKeyActive
+		}
+	}
+	return nil
+}
+
+func (p *fakeKeyProvider) RetireSigningKeys(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newBox(t *testing.T) *secretbox.Box {
+	t.Helper()
+
+	raw := make([]byte, secretbox.KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return box
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	provider := &fakeKeyProvider{}
+	keyring := jwt.NewKeyring(nil)
+
+	keys := New(log, provider, keyring, nil, newBox(t), time.Hour, time.Minute)
+
+	// new key is only added as pending
+	pending, err := keys.Rotate(ctx, "EdDSA")
+	if !errors.Is(err, ErrKeyNotReady) {
+		t.Fatalf("Rotate error = %v, want %v", err, ErrKeyNotReady)
+	}
+	if pending.State != models.SigningKeyPending {
+		t.Fatalf("State = %q, want %q", pending.State, models.SigningKeyPending)
+	}
+	if bytes.Contains(provider.keys[0].PrivateKey, []byte(pemPrefix)) {
+		t.Fatal("private key is stored in plaintext")
+	}
+
+	if err := keys.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, ok := keyring.Active(); ok {
+		t.Fatal("pending key is active")
+	}
+	if _, ok := keyring.Get(pending.ID); !ok {
+		t.Fatal("pending key is not published")
+	}
+
+	// pending key is activated once it is published long enough
+	provider.keys[0].CreatedAt = time.Now().UTC().Add(-time.Minute)
+
+	active, err := keys.Rotate(ctx, "EdDSA")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if active.ID != pending.ID || active.State != models.SigningKeyActive {
+		t.Fatalf("Rotate = %s %s, want %s active", active.ID, active.State, pending.ID)
+	}
+
+	if err := keys.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if key, ok := keyring.Active(); !ok || key.ID != pending.ID {
+		t.Fatalf("active key = %q, want %q", key.ID, pending.ID)
+	}
+}
+
+func TestAddNotConfigured(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := New(log, &fakeKeyProvider{}, jwt.NewKeyring(nil), nil, nil, time.Hour, time.Minute)
+
+	if _, err := keys.Add(context.Background(), "EdDSA"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("Add error = %v, want %v", err, ErrNotConfigured)
+	}
+}
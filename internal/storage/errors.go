@@ -3,7 +3,19 @@ package storage
 import "errors"
 
 var (
-	ErrUserExist    = errors.New("user is already exists")
-	ErrUserNotFound = errors.New("user is not found")
-	ErrAppNotFound  = errors.New("app is not found")
-)
\ No newline at end of file
+	ErrUserExist              = errors.New("user is already exists")
+	ErrUserNotFound           = errors.New("user is not found")
+	ErrAppNotFound            = errors.New("app is not found")
+	ErrRefreshTokenNotFound   = errors.New("refresh token is not found")
+	ErrRefreshTokenUsed       = errors.New("refresh token is already used")
+	ErrSigningKeyNotFound     = errors.New("signing key is not found")
+	ErrOneTimeTokenNotFound   = errors.New("one time token is not found")
+	ErrTOTPNotFound           = errors.New("totp is not found")
+	ErrTOTPStepUsed           = errors.New("totp code is already used")
+	ErrRecoveryCodeNotFound   = errors.New("recovery code is not found")
+	ErrPasskeyExist           = errors.New("passkey is already registered")
+	ErrPasskeyNotFound        = errors.New("passkey is not found")
+	ErrPasskeySessionNotFound = errors.New("passkey session is not found")
+	ErrLoginCodeNotFound      = errors.New("login code is not found")
+	ErrLoginCodeMismatch      = errors.New("login code does not match")
+)
@@ -0,0 +1,291 @@
+// Package memstorage keeps users and apps in memory. It is meant for tests
+// and local development and returns the same errors as sqlstorage.
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+type Storage struct {
+	mu     sync.RWMutex
+	lastID int32
+	users  map[int32]models.User
+	// history is previous password hashes of user, the oldest first
+	history map[int32][][]byte
+	// warned is when user was warned about password expiry last time
+	warned map[int32]time.Time
+	apps   map[int]models.App
+}
+
+func New() *Storage {
+	return &Storage{
+		users:   make(map[int32]models.User),
+		history: make(map[int32][][]byte),
+		warned:  make(map[int32]time.Time),
+		apps:    make(map[int]models.App),
+	}
+}
+
+// SaveApp adds or replaces app, apps are managed by migrations in postgres.
+func (s *Storage) SaveApp(app models.App) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apps[app.ID] = copyApp(app)
+}
+
+func (s *Storage) SaveUser(ctx context.Context, email, username string, hashedPass []byte) error {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slug := strings.ToLower(username)
+	if s.taken(0, email, slug) {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+	}
+
+	now := time.Now().UTC()
+
+	s.lastID++
+	s.users[s.lastID] = models.User{
+		ID:                 s.lastID,
+		Email:              email,
+		Username:           username,
+		Slug:               slug,
+		HashedPass:         clone(hashedPass),
+		LastPasswordChange: now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUserByID(ctx context.Context, userID int) (models.User, error) {
+	const op = "storage.memory.GetUserByID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[int32(userID)]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return copyUser(user), nil
+}
+
+func (s *Storage) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.memory.GetUserByEmail"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return copyUser(user), nil
+		}
+	}
+
+	return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+}
+
+// PasswordHistory returns up to limit previous password hashes of user,
+// the latest first.
+func (s *Storage) PasswordHistory(ctx context.Context, userID int32, limit int) ([][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.history[userID]
+
+	var hashes [][]byte
+	for i := len(history) - 1; i >= 0 && len(hashes) < limit; i-- {
+		hashes = append(hashes, clone(history[i]))
+	}
+
+	return hashes, nil
+}
+
+// PatchUsername sets username of user.
+// Username used by another user is storage.ErrUserExist.
+func (s *Storage) PatchUsername(ctx context.Context, user models.User, username string) error {
+	const op = "storage.memory.PatchUsername"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slug := strings.ToLower(username)
+	if s.taken(user.ID, "", slug) {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+	}
+
+	s.update(user.ID, func(u *models.User) {
+		u.Username = username
+		u.Slug = slug
+	})
+
+	return nil
+}
+
+// PatchPassword sets new password of user. Replaced hash is kept in
+// password history, only the latest history hashes are kept.
+func (s *Storage) PatchPassword(ctx context.Context, user models.User, password []byte, history int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[user.ID]
+	if !ok {
+		return nil
+	}
+
+	hashes := s.history[user.ID]
+	if history > 0 {
+		hashes = append(hashes, clone(current.HashedPass))
+	}
+	if len(hashes) > max(history, 0) {
+		hashes = hashes[len(hashes)-max(history, 0):]
+	}
+	s.history[user.ID] = hashes
+
+	s.update(user.ID, func(u *models.User) {
+		u.HashedPass = clone(password)
+		u.LastPasswordChange = u.UpdatedAt
+	})
+
+	return nil
+}
+
+// PatchPasswordHash replaces hash of the same password, e.g. made with
+// outdated parameters. Unlike PatchPassword, tokens issued before stay valid.
+func (s *Storage) PatchPasswordHash(ctx context.Context, user models.User, password []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.users[user.ID]; ok {
+		current.HashedPass = clone(password)
+		s.users[user.ID] = current
+	}
+
+	return nil
+}
+
+// PatchEmail sets confirmed new email of user.
+// Email used by another user is storage.ErrUserExist.
+func (s *Storage) PatchEmail(ctx context.Context, user models.User, email string) error {
+	const op = "storage.memory.PatchEmail"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.taken(user.ID, email, "") {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+	}
+
+	s.update(user.ID, func(u *models.User) {
+		u.Email = email
+		u.EmailVerifiedAt = u.UpdatedAt
+	})
+
+	return nil
+}
+
+// PatchEmailVerified marks user's current email as verified.
+func (s *Storage) PatchEmailVerified(ctx context.Context, user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.users[user.ID]; !ok || current.IsEmailVerified() {
+		return nil
+	}
+
+	s.update(user.ID, func(u *models.User) {
+		u.EmailVerifiedAt = u.UpdatedAt
+	})
+
+	return nil
+}
+
+func (s *Storage) PatchPasswordExpiryWarned(ctx context.Context, user models.User, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.ID]; !ok {
+		return false, nil
+	}
+	if warnedAt, ok := s.warned[user.ID]; ok && !warnedAt.Before(since) {
+		return false, nil
+	}
+
+	s.warned[user.ID] = time.Now().UTC()
+
+	return true, nil
+}
+
+func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
+	const op = "storage.memory.App"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return app, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return copyApp(app), nil
+}
+
+// taken reports whether email or slug belongs to user other than userID,
+// empty values are not checked. Caller holds the lock.
+func (s *Storage) taken(userID int32, email, slug string) bool {
+	for id, user := range s.users {
+		if id == userID {
+			continue
+		}
+		if email != "" && user.Email == email || slug != "" && user.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// update changes user and sets updated_at, missing user is skipped
+// as postgres UPDATE does. Caller holds the lock.
+func (s *Storage) update(userID int32, fn func(u *models.User)) {
+	user, ok := s.users[userID]
+	if !ok {
+		return
+	}
+
+	user.UpdatedAt = time.Now().UTC()
+	fn(&user)
+	s.users[userID] = user
+}
+
+// copyUser returns user not sharing slices with stored one.
+func copyUser(user models.User) models.User {
+	user.HashedPass = clone(user.HashedPass)
+	user.Roles = append([]string(nil), user.Roles...)
+	return user
+}
+
+func copyApp(app models.App) models.App {
+	app.Scopes = append([]string(nil), app.Scopes...)
+	app.PasswordPolicy = clone(app.PasswordPolicy)
+	return app
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,16 @@
+package memstorage
+
+import (
+	"testing"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		s := New()
+		s.SaveApp(models.App{ID: storagetest.TestAppID, Name: "test app", Secret: "secret"})
+		return s
+	})
+}
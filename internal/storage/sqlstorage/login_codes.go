@@ -0,0 +1,116 @@
+package sqlstorage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// SaveLoginCode saves code and drops unused codes of the same user and app,
+// so only the latest sent code is valid.
+func (s *Storage) SaveLoginCode(ctx context.Context, code models.LoginCode) error {
+	const op = "storage.postgres.SaveLoginCode"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM login_codes
+		WHERE user_id = $1 AND app_id = $2 AND used_at IS NULL`,
+		code.UserID,
+		code.AppID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO login_codes (user_id, app_id, code_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, code.UserID, code.AppID, code.CodeHash, code.ExpiresAt, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseLoginCode marks latest code of user and app as used if hash matches.
+// Mismatch costs one attempt and is storage.ErrLoginCodeMismatch.
+// Used, expired and exhausted codes are storage.ErrLoginCodeNotFound.
+func (s *Storage) UseLoginCode(ctx context.Context, userID int32, appID int, codeHash []byte, maxAttempts int) error {
+	const op = "storage.postgres.UseLoginCode"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	var code models.LoginCode
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id, code_hash, attempts
+		FROM login_codes
+		WHERE user_id = $1 AND app_id = $2 AND used_at IS NULL AND expires_at > $3 AND attempts < $4
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE`,
+		userID,
+		appID,
+		now,
+		maxAttempts,
+	).Scan(&code.ID, &code.CodeHash, &code.Attempts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%s: %w", op, storage.ErrLoginCodeNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !bytes.Equal(code.CodeHash, codeHash) {
+		_, err = tx.ExecContext(ctx, `UPDATE login_codes SET attempts = attempts + 1 WHERE id = $1`, code.ID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return fmt.Errorf("%s: %w", op, storage.ErrLoginCodeMismatch)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE login_codes SET used_at = $1 WHERE id = $2`, now, code.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
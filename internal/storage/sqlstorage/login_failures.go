@@ -0,0 +1,99 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+)
+
+// LoginLockedUntil returns the latest lock of subjects,
+// it is zero if none of them is locked.
+func (s *Storage) LoginLockedUntil(ctx context.Context, subjects []string) (time.Time, error) {
+	const op = "storage.postgres.LoginLockedUntil"
+
+	var lockedUntil sql.NullTime
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT MAX(locked_until)
+		FROM login_failures
+		WHERE subject = ANY($1) AND locked_until > $2`,
+		pq.Array(subjects),
+		time.Now().UTC(),
+	).Scan(&lockedUntil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lockedUntil.Time, nil
+}
+
+// AddLoginFailure counts failed login of subject and returns its failures.
+// Failures are counted from scratch if the last one is older than since.
+func (s *Storage) AddLoginFailure(ctx context.Context, subject string, since time.Time) (models.LoginFailure, error) {
+	const op = "storage.postgres.AddLoginFailure"
+
+	failure := models.LoginFailure{Subject: subject}
+
+	var lockedUntil sql.NullTime
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT
+		INTO login_failures (subject, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (subject) DO UPDATE
+		SET failures = CASE
+				WHEN login_failures.last_failure_at < $3 THEN 1
+				ELSE login_failures.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures, last_failure_at, locked_until`,
+		subject,
+		time.Now().UTC(),
+		since,
+	).Scan(&failure.Failures, &failure.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return failure, fmt.Errorf("%s: %w", op, err)
+	}
+	failure.LockedUntil = lockedUntil.Time
+
+	return failure, nil
+}
+
+// LockLogin rejects logins of subject until given time.
+func (s *Storage) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	const op = "storage.postgres.LockLogin"
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE login_failures SET locked_until = $1 WHERE subject = $2`,
+		until,
+		subject,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetLoginFailures forgets failures and lock of subject.
+func (s *Storage) ResetLoginFailures(ctx context.Context, subject string) error {
+	const op = "storage.postgres.ResetLoginFailures"
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM login_failures WHERE subject = $1`,
+		subject,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
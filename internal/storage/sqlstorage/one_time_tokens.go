@@ -0,0 +1,133 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// SaveOneTimeToken saves token and drops unused tokens of the same user
+// and purpose, so only the latest sent token is valid.
+func (s *Storage) SaveOneTimeToken(ctx context.Context, token models.OneTimeToken) error {
+	const op = "storage.postgres.SaveOneTimeToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM one_time_tokens
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		token.UserID,
+		token.Purpose,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO one_time_tokens (user_id, purpose, payload, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(
+		ctx,
+		token.UserID,
+		token.Purpose,
+		token.Payload,
+		token.TokenHash,
+		token.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetOneTimeToken returns unused and unexpired token without using it.
+func (s *Storage) GetOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error) {
+	const op = "storage.postgres.GetOneTimeToken"
+	var token models.OneTimeToken
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, purpose, payload, token_hash, expires_at, created_at
+		FROM one_time_tokens
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3`,
+		purpose,
+		tokenHash,
+		time.Now().UTC(),
+	)
+
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.Payload, &token.TokenHash,
+		&token.ExpiresAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return token, fmt.Errorf("%s: %w", op, storage.ErrOneTimeTokenNotFound)
+		}
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// UseOneTimeToken marks unused and unexpired token as used and returns it.
+// Only one caller can use the token, others get storage.ErrOneTimeTokenNotFound.
+func (s *Storage) UseOneTimeToken(ctx context.Context, purpose string, tokenHash []byte) (models.OneTimeToken, error) {
+	const op = "storage.postgres.UseOneTimeToken"
+	var token models.OneTimeToken
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE one_time_tokens
+		SET used_at = $1
+		WHERE purpose = $2 AND token_hash = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING id, user_id, purpose, payload, token_hash, expires_at, used_at, created_at`,
+	)
+	if err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := stmt.QueryRowContext(ctx, time.Now().UTC(), purpose, tokenHash)
+
+	err = row.Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.Payload, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return token, fmt.Errorf("%s: %w", op, storage.ErrOneTimeTokenNotFound)
+		}
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
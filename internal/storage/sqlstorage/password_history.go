@@ -0,0 +1,79 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PasswordHistory returns up to limit previous password hashes of user,
+// the latest first.
+func (s *Storage) PasswordHistory(ctx context.Context, userID int32, limit int) ([][]byte, error) {
+	const op = "storage.postgres.PasswordHistory"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT hashed_password
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT $2`,
+		userID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var hashes [][]byte
+	for rows.Next() {
+		var hash []byte
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hashes, nil
+}
+
+// savePasswordHistory moves current hash of user to history and prunes
+// all but keep latest ones. History is dropped if keep is zero.
+func savePasswordHistory(ctx context.Context, tx *sql.Tx, userID int32, keep int) error {
+	if keep > 0 {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT
+			INTO password_history (user_id, hashed_password, created_at)
+			SELECT id, hashed_password, $2
+			FROM users
+			WHERE id = $1`,
+			userID,
+			time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id
+			FROM password_history
+			WHERE user_id = $1
+			ORDER BY id DESC
+			LIMIT $2
+		)`,
+		userID,
+		max(keep, 0),
+	)
+
+	return err
+}
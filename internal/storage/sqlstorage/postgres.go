@@ -3,16 +3,21 @@ package sqlstorage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/lib/pq"
 
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
 	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
 )
 
+// uniqueViolation is postgres error code for unique constraint violation.
+const uniqueViolation = "23505"
+
 type Storage struct {
 	db *sql.DB
 }
@@ -65,11 +70,10 @@ func (s *Storage) SaveUser(ctx context.Context, email, username string, hashedPa
 		now,
 	) 
 	if err != nil {
-		// TODO: handle error with sql errors
-		// var sqlErr sql.ErrNoRows
-		// if errors.Is(err, &sqlErr) && sqlErr.ExtendedCode == sql.ErrConstraintUnique{
-		// 	return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
-		// }
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -78,6 +82,26 @@ func (s *Storage) SaveUser(ctx context.Context, email, username string, hashedPa
 	return nil
 }
 
+// userColumns are selected for models.User, see scanUser.
+const userColumns = `id, email, username, slug, hashed_password, roles, email_verified_at,
+	last_password_change, created_at, updated_at`
+
+func scanUser(row *sql.Row, user *models.User) error {
+	var emailVerifiedAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Username, &user.Slug, &user.HashedPass, pq.Array(&user.Roles),
+		&emailVerifiedAt, &user.LastPasswordChange, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	user.EmailVerifiedAt = emailVerifiedAt.Time
+
+	return nil
+}
+
 func (s *Storage) GetUserByID(ctx context.Context, userID int) (models.User, error) {
 	const op = "storage.postgres.GetUserByID"
 	var user models.User
@@ -86,7 +110,7 @@ func (s *Storage) GetUserByID(ctx context.Context, userID int) (models.User, err
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(
-		`SELECT id, email username, slug, hashed_password, last_password_change, created_at, updated_at  
+		`SELECT `+userColumns+`
 		FROM users
 		WHERE id = $1`,
 	)
@@ -96,10 +120,7 @@ func (s *Storage) GetUserByID(ctx context.Context, userID int) (models.User, err
 
 	row := stmt.QueryRowContext(ctx, userID) 
 
-	err = row.Scan(
-		&user.ID, &user.Email, &user.Username, &user.Slug, &user.HashedPass,
-		&user.LastPasswordChange, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err = scanUser(row, &user)
 	if err != nil {
 		if err == sql.ErrNoRows{
 			return user, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -120,9 +141,9 @@ func (s *Storage) GetUserByEmail(ctx context.Context, email string) (models.User
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(
-		`SELECT id, email, username, slug, hashed_password, last_password_change, created_at, updated_at  
+		`SELECT `+userColumns+`
 		FROM users
-		WHERE email like $1`,
+		WHERE email = $1`,
 	)
 	if err != nil {
 		return user, fmt.Errorf("%s: %w", op, err)
@@ -130,10 +151,7 @@ func (s *Storage) GetUserByEmail(ctx context.Context, email string) (models.User
 
 	row := stmt.QueryRowContext(ctx, email) 
 
-	err = row.Scan(
-		&user.ID, &user.Email, &user.Username, &user.Slug, &user.HashedPass,
-		&user.LastPasswordChange, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err = scanUser(row, &user)
 	if err != nil {
 		if err == sql.ErrNoRows{
 			return user, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -146,9 +164,10 @@ func (s *Storage) GetUserByEmail(ctx context.Context, email string) (models.User
 	return user, nil
 }
 
+// PatchUsername sets username of user.
+// Username used by another user is storage.ErrUserExist.
 func (s *Storage) PatchUsername(ctx context.Context, user models.User, username string) error {
 	const op = "storage.postgres.PatchUsername"
-	// TODO: handle
 
 	tx, _ := s.db.Begin()
 	defer tx.Rollback()
@@ -170,6 +189,10 @@ func (s *Storage) PatchUsername(ctx context.Context, user models.User, username
 
 	_, err = stmt.ExecContext(ctx, username, slug, now, user.ID)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -178,13 +201,21 @@ func (s *Storage) PatchUsername(ctx context.Context, user models.User, username
 	return nil
 }
 
-func (s *Storage) PatchPassword(ctx context.Context, user models.User, password []byte) error {
+// PatchPassword sets new password of user. Replaced hash is kept in
+// password history, only the latest history hashes are kept.
+func (s *Storage) PatchPassword(ctx context.Context, user models.User, password []byte, history int) error {
 	const op = "storage.postgres.PatchPassword"
-	// TODO: handle
 
-	tx, _ := s.db.Begin()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	defer tx.Rollback()
-	
+
+	if err := savePasswordHistory(ctx, tx, user.ID, history); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	stmt, err := tx.Prepare(
 		`UPDATE users 
 		SET 
@@ -204,11 +235,156 @@ func (s *Storage) PatchPassword(ctx context.Context, user models.User, password
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	tx.Commit()
+	// history and new password are saved together or not at all
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PatchPasswordHash replaces hash of the same password, e.g. made with
+// outdated parameters. Unlike PatchPassword, tokens issued before stay valid.
+func (s *Storage) PatchPasswordHash(ctx context.Context, user models.User, password []byte) error {
+	const op = "storage.postgres.PatchPasswordHash"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE users
+		SET hashed_password = $1
+		WHERE id = $2`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, password, user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PatchEmail sets confirmed new email of user.
+// Email used by another user is storage.ErrUserExist.
+func (s *Storage) PatchEmail(ctx context.Context, user models.User, email string) error {
+	const op = "storage.postgres.PatchEmail"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE users
+		SET
+			email = $1,
+			email_verified_at = $2,
+			updated_at = $2
+		WHERE id = $3`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, email, time.Now().UTC(), user.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExist)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	return nil
 }
 
+// PatchEmailVerified marks user's current email as verified.
+func (s *Storage) PatchEmailVerified(ctx context.Context, user models.User) error {
+	const op = "storage.postgres.PatchEmailVerified"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE users
+		SET
+			email_verified_at = $1,
+			updated_at = $1
+		WHERE id = $2 AND email_verified_at IS NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, time.Now().UTC(), user.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PatchPasswordExpiryWarned marks user warned about password expiry.
+// Warned is false if user is already warned after since, so instances
+// do not send the same warning twice.
+func (s *Storage) PatchPasswordExpiryWarned(ctx context.Context, user models.User, since time.Time) (bool, error) {
+	const op = "storage.postgres.PatchPasswordExpiryWarned"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE users
+		SET password_expiry_warned_at = $1
+		WHERE id = $2 AND (password_expiry_warned_at IS NULL OR password_expiry_warned_at < $3)`,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, time.Now().UTC(), user.ID, since)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
 func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 	const op = "storage.postgres.App"
 
@@ -218,7 +394,8 @@ func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 	var app models.App
 
 	stmt, err := tx.Prepare(
-		`SELECT id, name, secret
+		`SELECT id, name, secret, scopes, require_verified_email, allow_login_code, password_policy,
+			password_max_age_days
 		FROM apps
 		WHERE id = $1`,
 	)
@@ -228,8 +405,11 @@ func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 
 	row := stmt.QueryRowContext(ctx, appID) 
 
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	err = row.Scan(&app.ID, &app.Name, &app.Secret, pq.Array(&app.Scopes), &app.RequireVerifiedEmail, &app.AllowLoginCode, &app.PasswordPolicy, &app.PasswordMaxAgeDays)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return app, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
 		return app, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -0,0 +1,67 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/lib/ratelimit"
+)
+
+// TakeToken implements ratelimit.Backend, so instances share buckets.
+// Bucket row is locked while token is taken.
+func (s *Storage) TakeToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	const op = "storage.postgres.TakeToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT
+		INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING`,
+		key,
+		limit.Burst,
+		now,
+	)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var bucket ratelimit.Bucket
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`,
+		key,
+	).Scan(&bucket.Tokens, &bucket.UpdatedAt)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	bucket, res := ratelimit.Take(bucket, limit, now)
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2 WHERE key = $3`,
+		bucket.Tokens,
+		bucket.UpdatedAt,
+		key,
+	)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,98 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// ReplaceRecoveryCodes drops all recovery codes of user and saves new ones.
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, userID int32, codeHashes [][]byte) error {
+	const op = "storage.postgres.ReplaceRecoveryCodes"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO recovery_codes (user_id, code_hash, created_at)
+		VALUES ($1, $2, $3)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	for _, hash := range codeHashes {
+		if _, err := stmt.ExecContext(ctx, userID, hash, now); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks unused code as used and returns number of codes left.
+// Only one caller can use the code, others get storage.ErrRecoveryCodeNotFound.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID int32, codeHash []byte) (int, error) {
+	const op = "storage.postgres.UseRecoveryCode"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE recovery_codes
+		SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		time.Now().UTC(),
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrRecoveryCodeNotFound)
+	}
+
+	var left int
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	).Scan(&left)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return left, nil
+}
@@ -0,0 +1,163 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.postgres.SaveRefreshToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO refresh_tokens (user_id, app_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(
+		ctx,
+		token.UserID,
+		token.AppID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.postgres.GetRefreshToken"
+	var token models.RefreshToken
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT id, user_id, app_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`,
+	)
+	if err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := stmt.QueryRowContext(ctx, tokenHash)
+
+	var usedAt, revokedAt sql.NullTime
+
+	err = row.Scan(
+		&token.ID, &token.UserID, &token.AppID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &usedAt, &revokedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return token, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+		}
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token.UsedAt = usedAt.Time
+	token.RevokedAt = revokedAt.Time
+
+	if err := tx.Commit(); err != nil {
+		return token, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// UseRefreshToken marks token as used. Only one caller can use the token,
+// others get storage.ErrRefreshTokenUsed.
+func (s *Storage) UseRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.postgres.UseRefreshToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE refresh_tokens
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, time.Now().UTC(), token.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenUsed)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	const op = "storage.postgres.RevokeRefreshTokenFamily"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE family_id = $2 AND revoked_at IS NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, time.Now().UTC(), familyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
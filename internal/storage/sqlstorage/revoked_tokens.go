@@ -0,0 +1,80 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "storage.postgres.RevokeToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO revoked_tokens (jti, expires_at, revoked_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, jti, expiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// expired tokens are rejected anyway, no need to keep them
+	_, err = tx.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "storage.postgres.IsTokenRevoked"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT jti
+		FROM revoked_tokens
+		WHERE jti = $1`,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var res string
+
+	err = stmt.QueryRowContext(ctx, jti).Scan(&res)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,204 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+func (s *Storage) SaveSigningKey(ctx context.Context, key models.SigningKey) error {
+	const op = "storage.postgres.SaveSigningKey"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO signing_keys (kid, algorithm, private_key, state, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(
+		ctx,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		models.SigningKeyPending,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SigningKeys returns all keys except retired ones.
+func (s *Storage) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	const op = "storage.postgres.SigningKeys"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT kid, algorithm, private_key, state, created_at, activated_at, retiring_at, retired_at
+		FROM signing_keys
+		WHERE state <> $1
+		ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmt.QueryContext(ctx, models.SigningKeyRetired)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.SigningKey
+
+	for rows.Next() {
+		var (
+			key                                models.SigningKey
+			activatedAt, retiringAt, retiredAt sql.NullTime
+		)
+
+		err := rows.Scan(
+			&key.ID, &key.Algorithm, &key.PrivateKey, &key.State, &key.CreatedAt,
+			&activatedAt, &retiringAt, &retiredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		key.ActivatedAt = activatedAt.Time
+		key.RetiringAt = retiringAt.Time
+		key.RetiredAt = retiredAt.Time
+
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// ActivateSigningKey makes pending key active. Previous active key becomes retiring.
+func (s *Storage) ActivateSigningKey(ctx context.Context, kid string) error {
+	const op = "storage.postgres.ActivateSigningKey"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE signing_keys
+		SET
+			state = $1,
+			retiring_at = $2
+		WHERE state = $3`,
+		models.SigningKeyRetiring, now, models.SigningKeyActive,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE signing_keys
+		SET
+			state = $1,
+			activated_at = $2
+		WHERE kid = $3 AND state = $4`,
+		models.SigningKeyActive, now, kid, models.SigningKeyPending,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSigningKeyNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RetireSigningKeys retires keys which are retiring since before given time.
+func (s *Storage) RetireSigningKeys(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.RetireSigningKeys"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE signing_keys
+		SET
+			state = $1,
+			retired_at = $2
+		WHERE state = $3 AND retiring_at < $4`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(
+		ctx,
+		models.SigningKeyRetired,
+		time.Now().UTC(),
+		models.SigningKeyRetiring,
+		before.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
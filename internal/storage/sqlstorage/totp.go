@@ -0,0 +1,127 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// SaveTOTP saves pending enrollment replacing previous pending one.
+// Enabled enrollment is not replaced.
+func (s *Storage) SaveTOTP(ctx context.Context, totp models.TOTP) error {
+	const op = "storage.postgres.SaveTOTP"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO user_totp (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_totp.enabled_at IS NULL`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, totp.UserID, totp.Secret, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetTOTP(ctx context.Context, userID int32) (models.TOTP, error) {
+	const op = "storage.postgres.GetTOTP"
+	var totp models.TOTP
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return totp, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT user_id, secret, last_used_step, enabled_at, created_at
+		FROM user_totp
+		WHERE user_id = $1`,
+	)
+	if err != nil {
+		return totp, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := stmt.QueryRowContext(ctx, userID)
+
+	var enabledAt sql.NullTime
+
+	err = row.Scan(&totp.UserID, &totp.Secret, &totp.LastUsedStep, &enabledAt, &totp.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return totp, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+		}
+		return totp, fmt.Errorf("%s: %w", op, err)
+	}
+
+	totp.EnabledAt = enabledAt.Time
+
+	if err := tx.Commit(); err != nil {
+		return totp, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return totp, nil
+}
+
+// UseTOTPStep saves step of accepted code. Step not greater than the last
+// used one is storage.ErrTOTPStepUsed, so every code works only once.
+// Pending enrollment is enabled by its first code.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID int32, step int64) error {
+	const op = "storage.postgres.UseTOTPStep"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE user_totp
+		SET last_used_step = $1, enabled_at = COALESCE(enabled_at, $2)
+		WHERE user_id = $3 AND last_used_step < $1`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, step, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPStepUsed)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UseChallenge implements pow.Backend, so instances share used challenges.
+// Expired ones are deleted on the way, their ids are never issued again.
+func (s *Storage) UseChallenge(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	const op = "storage.postgres.UseChallenge"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM used_challenges WHERE expires_at < $1`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT
+		INTO used_challenges (id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING`,
+		id,
+		expiresAt.UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
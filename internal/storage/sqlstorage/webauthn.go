@@ -0,0 +1,287 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+const webAuthnCredentialColumns = `id, user_id, credential_id, public_key, attestation_type, aaguid, sign_count,
+	transports, backup_eligible, backup_state, created_at, last_used_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebAuthnCredential(row rowScanner, cred *models.WebAuthnCredential) error {
+	var lastUsedAt sql.NullTime
+	var signCount int64
+
+	err := row.Scan(
+		&cred.ID, &cred.UserID, &cred.CredentialID, &cred.PublicKey, &cred.AttestationType,
+		&cred.AAGUID, &signCount, pq.Array(&cred.Transports), &cred.BackupEligible, &cred.BackupState,
+		&cred.CreatedAt, &lastUsedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	cred.SignCount = uint32(signCount)
+	cred.LastUsedAt = lastUsedAt.Time
+
+	return nil
+}
+
+// SavePasskey saves registered passkey.
+// Credential registered already is storage.ErrPasskeyExist.
+func (s *Storage) SavePasskey(ctx context.Context, cred models.WebAuthnCredential) error {
+	const op = "storage.postgres.SavePasskey"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO webauthn_credentials (user_id, credential_id, public_key, attestation_type, aaguid,
+			sign_count, transports, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(
+		ctx,
+		cred.UserID,
+		cred.CredentialID,
+		cred.PublicKey,
+		cred.AttestationType,
+		cred.AAGUID,
+		int64(cred.SignCount),
+		pq.Array(cred.Transports),
+		cred.BackupEligible,
+		cred.BackupState,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrPasskeyExist)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Passkeys(ctx context.Context, userID int32) ([]models.WebAuthnCredential, error) {
+	const op = "storage.postgres.Passkeys"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var creds []models.WebAuthnCredential
+
+	for rows.Next() {
+		var cred models.WebAuthnCredential
+		if err := scanWebAuthnCredential(rows, &cred); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		creds = append(creds, cred)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return creds, nil
+}
+
+func (s *Storage) GetPasskey(ctx context.Context, credentialID []byte) (models.WebAuthnCredential, error) {
+	const op = "storage.postgres.GetPasskey"
+	var cred models.WebAuthnCredential
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return cred, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE credential_id = $1`,
+	)
+	if err != nil {
+		return cred, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = scanWebAuthnCredential(stmt.QueryRowContext(ctx, credentialID), &cred)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return cred, fmt.Errorf("%s: %w", op, storage.ErrPasskeyNotFound)
+		}
+		return cred, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return cred, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cred, nil
+}
+
+// UsePasskey saves sign counter and backup state of passkey after login.
+func (s *Storage) UsePasskey(ctx context.Context, cred models.WebAuthnCredential) error {
+	const op = "storage.postgres.UsePasskey"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`UPDATE webauthn_credentials
+		SET sign_count = $1, backup_state = $2, last_used_at = $3
+		WHERE credential_id = $4`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(ctx, int64(cred.SignCount), cred.BackupState, time.Now().UTC(), cred.CredentialID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SavePasskeySession saves ceremony state and drops expired ones.
+func (s *Storage) SavePasskeySession(ctx context.Context, session models.WebAuthnSession) error {
+	const op = "storage.postgres.SavePasskeySession"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM webauthn_sessions WHERE expires_at <= $1`, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.Prepare(
+		`INSERT
+		INTO webauthn_sessions (id_hash, user_id, app_id, data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmt.ExecContext(
+		ctx,
+		session.IDHash,
+		sql.NullInt32{Int32: session.UserID, Valid: session.UserID != 0},
+		sql.NullInt32{Int32: int32(session.AppID), Valid: session.AppID != 0},
+		session.Data,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UsePasskeySession deletes unexpired session and returns it, so every
+// ceremony is finished only once.
+func (s *Storage) UsePasskeySession(ctx context.Context, idHash []byte) (models.WebAuthnSession, error) {
+	const op = "storage.postgres.UsePasskeySession"
+	var session models.WebAuthnSession
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`DELETE FROM webauthn_sessions
+		WHERE id_hash = $1 AND expires_at > $2
+		RETURNING id_hash, user_id, app_id, data, expires_at`,
+	)
+	if err != nil {
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var userID, appID sql.NullInt32
+
+	err = stmt.QueryRowContext(ctx, idHash, time.Now().UTC()).Scan(
+		&session.IDHash, &userID, &appID, &session.Data, &session.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return session, fmt.Errorf("%s: %w", op, storage.ErrPasskeySessionNotFound)
+		}
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+
+	session.UserID = userID.Int32
+	session.AppID = int(appID.Int32)
+
+	if err := tx.Commit(); err != nil {
+		return session, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
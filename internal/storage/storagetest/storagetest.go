@@ -0,0 +1,384 @@
+// Package storagetest is test suite shared by storage implementations,
+// so they keep the same semantics as services expect.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/domain/models"
+	authsrvcs "github.com/rautaruukkipalich/go_auth_grpc/internal/services/auth"
+	"github.com/rautaruukkipalich/go_auth_grpc/internal/storage"
+)
+
+// TestAppID is app every storage under test must have,
+// postgres one is added by migrations.
+const TestAppID = 1
+
+// Storage is what auth service needs from users and apps storage.
+type Storage interface {
+	authsrvcs.UserSaver
+	authsrvcs.UserGetter
+	authsrvcs.UserPatcher
+	authsrvcs.AppProvider
+}
+
+// seq makes emails and usernames unique, so suite can run
+// against database shared with other runs.
+var seq atomic.Int64
+
+var tests = []struct {
+	name string
+	run  func(t *testing.T, s Storage)
+}{
+	{"SaveUser", testSaveUser},
+	{"SaveUserExist", testSaveUserExist},
+	{"UserNotFound", testUserNotFound},
+	{"EmailIsNotPattern", testEmailIsNotPattern},
+	{"App", testApp},
+	{"PatchUsername", testPatchUsername},
+	{"PatchEmail", testPatchEmail},
+	{"PatchEmailVerified", testPatchEmailVerified},
+	{"PatchPassword", testPatchPassword},
+	{"PasswordHistory", testPasswordHistory},
+	{"PatchPasswordHash", testPatchPasswordHash},
+	{"PatchPasswordExpiryWarned", testPatchPasswordExpiryWarned},
+}
+
+// Run runs suite against storage made by newStorage for every test.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStorage(t))
+		})
+	}
+}
+
+func testSaveUser(t *testing.T, s Storage) {
+	ctx := context.Background()
+	email, username := uniqueUser()
+	before := time.Now().UTC().Add(-time.Second)
+
+	if err := s.SaveUser(ctx, email, username, []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	switch {
+	case user.ID == 0:
+		t.Error("ID is not set")
+	case user.Email != email:
+		t.Errorf("Email = %q, want %q", user.Email, email)
+	case user.Username != username:
+		t.Errorf("Username = %q, want %q", user.Username, username)
+	case !bytes.Equal(user.HashedPass, []byte("hash")):
+		t.Errorf("HashedPass = %q, want %q", user.HashedPass, "hash")
+	case user.IsEmailVerified():
+		t.Error("email is verified")
+	case user.LastPasswordChange.Before(before):
+		t.Errorf("LastPasswordChange = %s, want after %s", user.LastPasswordChange, before)
+	}
+
+	byID, err := s.GetUserByID(ctx, int(user.ID))
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if byID.Email != email {
+		t.Errorf("GetUserByID Email = %q, want %q", byID.Email, email)
+	}
+}
+
+func testSaveUserExist(t *testing.T, s Storage) {
+	ctx := context.Background()
+	email, username := uniqueUser()
+	otherEmail, otherUsername := uniqueUser()
+
+	if err := s.SaveUser(ctx, email, username, []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		username string
+	}{
+		{"same email", email, otherUsername},
+		{"same username", otherEmail, username},
+		// usernames are compared case insensitive
+		{"same username in other case", otherEmail, strings.ToUpper(username)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SaveUser(ctx, tt.email, tt.username, []byte("hash"))
+			if !errors.Is(err, storage.ErrUserExist) {
+				t.Fatalf("SaveUser error = %v, want %v", err, storage.ErrUserExist)
+			}
+		})
+	}
+}
+
+func testUserNotFound(t *testing.T, s Storage) {
+	ctx := context.Background()
+	email, _ := uniqueUser()
+
+	if _, err := s.GetUserByEmail(ctx, email); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByEmail error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if _, err := s.GetUserByID(ctx, math.MaxInt32); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByID error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+// testEmailIsNotPattern checks email is matched exactly, so wildcards
+// can not be used to log in as somebody else.
+func testEmailIsNotPattern(t *testing.T, s Storage) {
+	ctx := context.Background()
+	newUser(t, s)
+
+	for _, email := range []string{"%@%.%", "user%", "_%"} {
+		if _, err := s.GetUserByEmail(ctx, email); !errors.Is(err, storage.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail(%q) error = %v, want %v", email, err, storage.ErrUserNotFound)
+		}
+	}
+}
+
+func testApp(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	app, err := s.App(ctx, TestAppID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if app.ID != TestAppID {
+		t.Errorf("ID = %d, want %d", app.ID, TestAppID)
+	}
+
+	if _, err := s.App(ctx, math.MaxInt32); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("App error = %v, want %v", err, storage.ErrAppNotFound)
+	}
+}
+
+func testPatchUsername(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+	other := newUser(t, s)
+
+	err := s.PatchUsername(ctx, user, strings.ToUpper(other.Username))
+	if !errors.Is(err, storage.ErrUserExist) {
+		t.Fatalf("PatchUsername error = %v, want %v", err, storage.ErrUserExist)
+	}
+
+	_, username := uniqueUser()
+	if err := s.PatchUsername(ctx, user, username); err != nil {
+		t.Fatalf("PatchUsername: %v", err)
+	}
+
+	got := getUser(t, s, user.ID)
+	if got.Username != username {
+		t.Errorf("Username = %q, want %q", got.Username, username)
+	}
+}
+
+func testPatchEmail(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+	other := newUser(t, s)
+
+	err := s.PatchEmail(ctx, user, other.Email)
+	if !errors.Is(err, storage.ErrUserExist) {
+		t.Fatalf("PatchEmail error = %v, want %v", err, storage.ErrUserExist)
+	}
+
+	email, _ := uniqueUser()
+	if err := s.PatchEmail(ctx, user, email); err != nil {
+		t.Fatalf("PatchEmail: %v", err)
+	}
+
+	got := getUser(t, s, user.ID)
+	if got.Email != email {
+		t.Errorf("Email = %q, want %q", got.Email, email)
+	}
+	if !got.IsEmailVerified() {
+		t.Error("new email is not verified")
+	}
+}
+
+func testPatchEmailVerified(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+
+	if err := s.PatchEmailVerified(ctx, user); err != nil {
+		t.Fatalf("PatchEmailVerified: %v", err)
+	}
+
+	verified := getUser(t, s, user.ID)
+	if !verified.IsEmailVerified() {
+		t.Fatal("email is not verified")
+	}
+
+	// verifying again keeps the first time
+	if err := s.PatchEmailVerified(ctx, verified); err != nil {
+		t.Fatalf("PatchEmailVerified: %v", err)
+	}
+	if got := getUser(t, s, user.ID); !got.EmailVerifiedAt.Equal(verified.EmailVerifiedAt) {
+		t.Errorf("EmailVerifiedAt = %s, want %s", got.EmailVerifiedAt, verified.EmailVerifiedAt)
+	}
+}
+
+func testPatchPassword(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+
+	if err := s.PatchPassword(ctx, user, []byte("new hash"), 0); err != nil {
+		t.Fatalf("PatchPassword: %v", err)
+	}
+
+	got := getUser(t, s, user.ID)
+	if !bytes.Equal(got.HashedPass, []byte("new hash")) {
+		t.Errorf("HashedPass = %q, want %q", got.HashedPass, "new hash")
+	}
+	if got.LastPasswordChange.Before(user.LastPasswordChange) {
+		t.Errorf("LastPasswordChange = %s, want after %s", got.LastPasswordChange, user.LastPasswordChange)
+	}
+}
+
+func testPasswordHistory(t *testing.T, s Storage) {
+	tests := []struct {
+		name    string
+		history int
+		changes int
+		want    []string
+	}{
+		{"off", 0, 3, nil},
+		{"not full", 5, 2, []string{"hash 1", "hash 0"}},
+		{"pruned", 2, 4, []string{"hash 3", "hash 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			user := newUser(t, s)
+
+			if err := s.PatchPasswordHash(ctx, user, []byte("hash 0")); err != nil {
+				t.Fatalf("PatchPasswordHash: %v", err)
+			}
+			for i := 1; i <= tt.changes; i++ {
+				hash := []byte(fmt.Sprintf("hash %d", i))
+				if err := s.PatchPassword(ctx, user, hash, tt.history); err != nil {
+					t.Fatalf("PatchPassword: %v", err)
+				}
+			}
+
+			// limit above kept count returns everything kept
+			hashes, err := s.PasswordHistory(ctx, user.ID, tt.history+1)
+			if err != nil {
+				t.Fatalf("PasswordHistory: %v", err)
+			}
+
+			if len(hashes) != len(tt.want) {
+				t.Fatalf("PasswordHistory = %q, want %q", hashes, tt.want)
+			}
+			for i := range hashes {
+				if string(hashes[i]) != tt.want[i] {
+					t.Fatalf("PasswordHistory = %q, want %q", hashes, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func testPatchPasswordHash(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+
+	if err := s.PatchPasswordHash(ctx, user, []byte("rehashed")); err != nil {
+		t.Fatalf("PatchPasswordHash: %v", err)
+	}
+
+	got := getUser(t, s, user.ID)
+	if !bytes.Equal(got.HashedPass, []byte("rehashed")) {
+		t.Errorf("HashedPass = %q, want %q", got.HashedPass, "rehashed")
+	}
+	if !got.LastPasswordChange.Equal(user.LastPasswordChange) {
+		t.Errorf("LastPasswordChange = %s, want %s", got.LastPasswordChange, user.LastPasswordChange)
+	}
+
+	hashes, err := s.PasswordHistory(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("PasswordHistory: %v", err)
+	}
+	if len(hashes) != 0 {
+		t.Errorf("PasswordHistory = %q, want empty", hashes)
+	}
+}
+
+func testPatchPasswordExpiryWarned(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := newUser(t, s)
+	since := time.Now().UTC().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  bool
+	}{
+		{"first", since, true},
+		{"warned after since", since, false},
+		{"warned before since", time.Now().UTC().Add(time.Second), true},
+	}
+
+	for _, tt := range tests {
+		warned, err := s.PatchPasswordExpiryWarned(ctx, user, tt.since)
+		if err != nil {
+			t.Fatalf("%s: PatchPasswordExpiryWarned: %v", tt.name, err)
+		}
+		if warned != tt.want {
+			t.Fatalf("%s: PatchPasswordExpiryWarned = %v, want %v", tt.name, warned, tt.want)
+		}
+	}
+}
+
+func uniqueUser() (email, username string) {
+	n := seq.Add(1)
+	id := fmt.Sprintf("%d%d", time.Now().UnixNano(), n)
+	return "user" + id + "@example.com", "user" + id
+}
+
+func newUser(t *testing.T, s Storage) models.User {
+	t.Helper()
+
+	email, username := uniqueUser()
+	if err := s.SaveUser(context.Background(), email, username, []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := s.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	return user
+}
+
+func getUser(t *testing.T, s Storage, id int32) models.User {
+	t.Helper()
+
+	user, err := s.GetUserByID(context.Background(), int(id))
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	return user
+}
@@ -5,7 +5,8 @@ import (
 )
 
 var (
-	ErrEmptyAppID = fmt.Errorf("empty app id")
+	ErrEmptyAppID     = fmt.Errorf("empty app id")
+	ErrEmptyAppSecret = fmt.Errorf("empty app secret")
 )
 
 const (
@@ -18,4 +19,12 @@ func ValidationAppID(appID int32) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func ValidationAppSecret(appSecret string) error {
+	if appSecret == EmptyString {
+		return ErrEmptyAppSecret
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"fmt"
+)
+
+var (
+	ErrEmptyLoginCode   = fmt.Errorf("empty code")
+	ErrInvalidLoginCode = fmt.Errorf("code must be 6 digits")
+)
+
+const LoginCodeLength = 6
+
+func ValidationLoginCode(code string) error {
+	if code == EmptyString {
+		return ErrEmptyLoginCode
+	}
+
+	if len(code) != LoginCodeLength {
+		return ErrInvalidLoginCode
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return ErrInvalidLoginCode
+		}
+	}
+
+	return nil
+}
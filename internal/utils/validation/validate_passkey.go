@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	ErrEmptyPasskeyCredential   = fmt.Errorf("empty passkey credential")
+	ErrInvalidPasskeyCredential = fmt.Errorf("passkey credential must be JSON")
+)
+
+func ValidationPasskeyCredential(credential []byte) error {
+	if len(credential) == 0 {
+		return ErrEmptyPasskeyCredential
+	}
+
+	if !json.Valid(credential) {
+		return ErrInvalidPasskeyCredential
+	}
+
+	return nil
+}
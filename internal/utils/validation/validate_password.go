@@ -2,18 +2,15 @@ package validation
 
 import (
 	"fmt"
-	"regexp"
 )
 
-const (
-	minLenPassword = 6
-	maxLenPassword = 30
-)
+// maxLenPassword only protects hashing from huge input,
+// password rules are checked by password policy.
+const maxLenPassword = 1024
 
 var (
-	ErrEmptyPassword = fmt.Errorf("empty password")
-	ErrInvalidPassword = fmt.Errorf("invalid password")
-	ErrInvalidPasswordLength = fmt.Errorf("invalid password. password length must be %d to %d", minLenPassword, maxLenPassword)
+	ErrEmptyPassword         = fmt.Errorf("empty password")
+	ErrInvalidPasswordLength = fmt.Errorf("invalid password. password length must be at most %d bytes", maxLenPassword)
 )
 
 func ValidationPassword(password string) error {
@@ -21,14 +18,9 @@ func ValidationPassword(password string) error {
 		return ErrEmptyPassword
 	}
 
-	pattern := fmt.Sprintf(`^.{%d,%d}$`, minLenPassword, maxLenPassword)
-	res, err := regexp.MatchString(pattern, password)
-	if err != nil {
-		return ErrInvalidPassword
-	}
-	if !res {
+	if len(password) > maxLenPassword {
 		return ErrInvalidPasswordLength
 	}
 
 	return nil
-}
\ No newline at end of file
+}
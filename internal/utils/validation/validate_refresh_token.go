@@ -0,0 +1,17 @@
+package validation
+
+import (
+	"fmt"
+)
+
+var (
+	ErrEmptyRefreshToken = fmt.Errorf("empty refresh token")
+)
+
+func ValidationRefreshToken(token string) error {
+	if token == EmptyString {
+		return ErrEmptyRefreshToken
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"fmt"
+)
+
+var (
+	ErrEmptyTOTPCode   = fmt.Errorf("empty code")
+	ErrInvalidTOTPCode = fmt.Errorf("code must be 6 digits")
+)
+
+const TOTPCodeLength = 6
+
+func ValidationTOTPCode(code string) error {
+	if code == EmptyString {
+		return ErrEmptyTOTPCode
+	}
+
+	if len(code) != TOTPCodeLength {
+		return ErrInvalidTOTPCode
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return ErrInvalidTOTPCode
+		}
+	}
+
+	return nil
+}
+
+// ValidationMFACode accepts TOTP code or recovery code,
+// recovery code format is checked by its hash lookup.
+func ValidationMFACode(code string) error {
+	if code == EmptyString {
+		return ErrEmptyTOTPCode
+	}
+
+	return nil
+}
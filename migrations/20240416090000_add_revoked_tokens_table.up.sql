@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti        VARCHAR NOT NULL PRIMARY KEY,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS signing_keys
+(
+    kid          VARCHAR NOT NULL PRIMARY KEY,
+    algorithm    VARCHAR NOT NULL,
+    private_key  BYTEA NOT NULL,
+    state        VARCHAR NOT NULL CHECK (state IN ('pending', 'active', 'retiring', 'retired')),
+    created_at   TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    activated_at TIMESTAMP WITHOUT TIME ZONE,
+    retiring_at  TIMESTAMP WITHOUT TIME ZONE,
+    retired_at   TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_one_active ON signing_keys (state) WHERE state = 'active';
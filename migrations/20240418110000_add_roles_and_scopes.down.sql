@@ -0,0 +1,5 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS roles;
+
+ALTER TABLE apps
+DROP COLUMN IF EXISTS scopes;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD roles TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE apps
+ADD scopes TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS one_time_tokens
+(
+    id         BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    VARCHAR NOT NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_purpose ON one_time_tokens (user_id, purpose);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD email_verified_at TIMESTAMP WITHOUT TIME ZONE;
+
+ALTER TABLE apps
+ADD require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
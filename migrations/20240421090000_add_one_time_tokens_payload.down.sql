@@ -0,0 +1,2 @@
+ALTER TABLE one_time_tokens
+DROP COLUMN IF EXISTS payload;
@@ -0,0 +1,2 @@
+ALTER TABLE one_time_tokens
+ADD payload VARCHAR NOT NULL DEFAULT '';
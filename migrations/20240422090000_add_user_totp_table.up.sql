@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        BIGINT NOT NULL PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret         BYTEA NOT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    enabled_at     TIMESTAMP WITHOUT TIME ZONE,
+    created_at     TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  BYTEA NOT NULL,
+    used_at    TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_code ON recovery_codes (user_id, code_hash);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id               BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id          BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id    BYTEA NOT NULL UNIQUE,
+    public_key       BYTEA NOT NULL,
+    attestation_type VARCHAR NOT NULL,
+    aaguid           BYTEA,
+    sign_count       BIGINT NOT NULL DEFAULT 0,
+    transports       TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible  BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    last_used_at     TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_sessions
+(
+    id_hash    BYTEA NOT NULL PRIMARY KEY,
+    user_id    BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER REFERENCES apps (id) ON DELETE CASCADE,
+    data       BYTEA NOT NULL,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_sessions_expires_at ON webauthn_sessions (expires_at);
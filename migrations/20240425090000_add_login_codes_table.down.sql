@@ -0,0 +1,4 @@
+ALTER TABLE apps
+DROP COLUMN IF EXISTS allow_login_code;
+
+DROP TABLE IF EXISTS login_codes;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS login_codes
+(
+    id         BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    code_hash  BYTEA NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_codes_user_app ON login_codes (user_id, app_id);
+
+ALTER TABLE apps
+ADD allow_login_code BOOLEAN NOT NULL DEFAULT FALSE;
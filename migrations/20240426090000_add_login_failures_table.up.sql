@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS login_failures
+(
+    subject         TEXT NOT NULL PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    locked_until    TIMESTAMP WITHOUT TIME ZONE
+);
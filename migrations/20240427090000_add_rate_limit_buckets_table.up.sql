@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limit_buckets
+(
+    key        TEXT NOT NULL PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
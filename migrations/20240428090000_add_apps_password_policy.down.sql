@@ -0,0 +1,2 @@
+ALTER TABLE apps
+DROP COLUMN IF EXISTS password_policy;
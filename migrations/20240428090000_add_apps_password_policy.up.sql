@@ -0,0 +1,2 @@
+ALTER TABLE apps
+ADD password_policy JSONB;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS password_history
+(
+    id              SERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    hashed_password BYTEA NOT NULL,
+    created_at      TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id, id);
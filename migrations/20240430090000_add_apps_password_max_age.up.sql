@@ -0,0 +1,2 @@
+ALTER TABLE apps
+ADD password_max_age_days INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD password_expiry_warned_at TIMESTAMP WITHOUT TIME ZONE;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS used_challenges
+(
+    id         TEXT NOT NULL PRIMARY KEY,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_used_challenges_expires_at ON used_challenges (expires_at);